@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"itmx_test/config"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newConfigCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the effective configuration",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "print",
+		Short: "Print the validated configuration after every override, with secrets redacted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			encoder := yaml.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent(2)
+			if err := encoder.Encode(cfg.Redacted()); err != nil {
+				return err
+			}
+			return encoder.Close()
+		},
+	})
+
+	return cmd
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"itmx_test/config"
+	"itmx_test/service/customer/repository"
+	"itmx_test/service/customer/usecase"
+	"itmx_test/service/entity"
+
+	"github.com/spf13/cobra"
+)
+
+func newCustomersCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "customers",
+		Short: "Export or import customers",
+	}
+
+	cmd.AddCommand(newCustomersExportCommand(cfg), newCustomersImportCommand(cfg))
+
+	return cmd
+}
+
+func newCustomersExportCommand(cfg *config.Config) *cobra.Command {
+	var (
+		format, output string
+		filter         entity.CustomerFilter
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write every customer matching the filters as CSV or NDJSON",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "" {
+				format = formatFromPath(output)
+			}
+
+			db, err := config.InitDB(cfg.Database)
+			if err != nil {
+				return err
+			}
+			cu := usecase.NewCustomerUsecase(repository.NewCustomerRepository(db))
+
+			var w io.Writer = cmd.OutOrStdout()
+			if output != "" && output != "-" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				w = file
+			}
+
+			buffered := bufio.NewWriter(w)
+			if err := cu.ExportCustomers(cliContext(cmd), buffered, format, &filter); err != nil {
+				return err
+			}
+			return buffered.Flush()
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&format, "format", "", "csv or ndjson (default from the output extension, else csv)")
+	flags.StringVarP(&output, "output", "o", "", "output file (default stdout)")
+	flags.StringVar(&filter.Name, "name", "", "only customers whose name matches")
+	flags.IntVar(&filter.MinAge, "min-age", 0, "only customers at least this old")
+	flags.IntVar(&filter.MaxAge, "max-age", 0, "only customers at most this old")
+
+	return cmd
+}
+
+func newCustomersImportCommand(cfg *config.Config) *cobra.Command {
+	var (
+		format, input string
+		mapping       map[string]string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Create customers from a CSV or NDJSON file, skipping invalid rows",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "" {
+				format = formatFromPath(input)
+			}
+
+			columns := map[string]string{}
+			for source, target := range mapping {
+				columns[strings.ToLower(strings.TrimSpace(source))] = strings.ToLower(strings.TrimSpace(target))
+			}
+
+			db, err := config.InitDB(cfg.Database)
+			if err != nil {
+				return err
+			}
+			cu := usecase.NewCustomerUsecase(repository.NewCustomerRepository(db))
+
+			var r io.Reader = cmd.InOrStdin()
+			if input != "" && input != "-" {
+				file, err := os.Open(input)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				r = file
+			}
+
+			report, err := cu.ImportCustomers(cliContext(cmd), bufio.NewReader(r), format, columns)
+			if report != nil {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				if encodeErr := encoder.Encode(report); encodeErr != nil && err == nil {
+					err = encodeErr
+				}
+			}
+			return err
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&format, "format", "", "csv or ndjson (default from the input extension, else csv)")
+	flags.StringVarP(&input, "file", "f", "", "input file (default stdin)")
+	flags.StringToStringVar(&mapping, "map", nil, "rename source columns, e.g. full_name=name,years=age")
+
+	return cmd
+}
+
+// formatFromPath picks the transfer format from a file extension, CSV unless it is .ndjson
+// or .jsonl
+func formatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return usecase.FormatNDJSON
+	default:
+		return usecase.FormatCSV
+	}
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"itmx_test/config"
+	"itmx_test/migration"
+
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply, roll back or list schema migrations",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply every pending migration",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				migrator, err := newMigrator(cfg)
+				if err != nil {
+					return err
+				}
+
+				applied, err := migrator.Up(cmd.Context())
+				for _, m := range applied {
+					fmt.Fprintf(cmd.OutOrStdout(), "applied  %04d_%s\n", m.Version, m.Name)
+				}
+				if err != nil {
+					return err
+				}
+				if len(applied) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "schema is up to date")
+				}
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "down [n]",
+			Short: "Roll back the last n migrations (default 1)",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				steps := 1
+				if len(args) > 0 {
+					n, err := strconv.Atoi(args[0])
+					if err != nil || n < 1 {
+						return fmt.Errorf("invalid number of migrations to roll back: %s", args[0])
+					}
+					steps = n
+				}
+
+				migrator, err := newMigrator(cfg)
+				if err != nil {
+					return err
+				}
+
+				reverted, err := migrator.Down(cmd.Context(), steps)
+				for _, m := range reverted {
+					fmt.Fprintf(cmd.OutOrStdout(), "reverted %04d_%s\n", m.Version, m.Name)
+				}
+				return err
+			},
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "List migrations and whether they are applied",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				migrator, err := newMigrator(cfg)
+				if err != nil {
+					return err
+				}
+
+				statuses, err := migrator.Status(cmd.Context())
+				if err != nil {
+					return err
+				}
+				for _, status := range statuses {
+					appliedAt := "pending"
+					if status.Applied {
+						appliedAt = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "%04d_%-30s %s\n", status.Version, status.Name, appliedAt)
+				}
+				return nil
+			},
+		},
+	)
+
+	return cmd
+}
+
+// newMigrator connects without the startup migration check, which would refuse a schema
+// that is behind
+func newMigrator(cfg *config.Config) (*migration.Migrator, error) {
+	db, err := config.Connect(cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	return migration.New(db)
+}
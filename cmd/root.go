@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"itmx_test/config"
+	"itmx_test/logging"
+	"itmx_test/service/entity"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// cliActor is the audit actor of every change made from the command line
+const cliActor = "cli"
+
+// Execute runs the command named by the process arguments
+func Execute() error {
+	return NewRootCommand().Execute()
+}
+
+// NewRootCommand builds the itmx command tree. Flags bound to config keys override the
+// values read from the config file.
+func NewRootCommand() *cobra.Command {
+	var configDir, configFile string
+	cfg := &config.Config{}
+
+	root := &cobra.Command{
+		Use:          "itmx",
+		Short:        "Customer service API and maintenance commands",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			loaded, err := config.Load(viper.GetViper(), config.LoadOptions{
+				Dir:  configDir,
+				Env:  appEnv(),
+				File: configFile,
+			})
+			if err != nil {
+				return err
+			}
+			*cfg = *loaded
+			return logging.Setup(logging.Config{Level: cfg.Log.Level, Format: cfg.Log.Format})
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&configDir, "config-dir", ".", "directory of config.yml and config.<env>.yml")
+	flags.StringVarP(&configFile, "config", "c", "", "single config file to read instead of the ones of --config-dir")
+	flags.String("db-driver", "", "database driver: sqlite, memory, mysql or postgres")
+	flags.String("db-dsn", "", "database data source name, overrides the other database settings")
+	flags.String("db-path", "", "SQLite database file")
+	bindFlag(flags.Lookup("db-driver"), "database.driver")
+	bindFlag(flags.Lookup("db-dsn"), "database.dsn")
+	bindFlag(flags.Lookup("db-path"), "database.path")
+
+	root.AddCommand(
+		newServeCommand(cfg),
+		newMigrateCommand(cfg),
+		newSeedCommand(cfg),
+		newCustomersCommand(cfg),
+		newConfigCommand(cfg),
+	)
+
+	return root
+}
+
+func bindFlag(flag *pflag.Flag, key string) {
+	if err := viper.BindPFlag(key, flag); err != nil {
+		panic(err)
+	}
+}
+
+// appEnv is the environment the process runs in, from APP_ENV
+func appEnv() string {
+	if env := os.Getenv("APP_ENV"); env != "" {
+		return env
+	}
+	return config.EnvDevelopment
+}
+
+func cliContext(cmd *cobra.Command) context.Context {
+	return entity.ContextWithAuditMeta(cmd.Context(), entity.AuditMeta{Actor: cliActor})
+}
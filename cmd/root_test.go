@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"itmx_test/config"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T) (dir string, configFile string) {
+	dir = t.TempDir()
+	configFile = filepath.Join(dir, "config.yml")
+	content := `admin:
+  token: admin-token-0123456789
+pagination:
+  cursor_secret: cursor-secret-0123456789
+database:
+  driver: sqlite
+  path: ` + filepath.Join(dir, "itmx.sqlite") + `
+  password: P@ssword1234
+  auto_migrate: true
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(content), 0o600))
+	return dir, configFile
+}
+
+func run(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Cleanup(viper.Reset)
+
+	var out bytes.Buffer
+	root := NewRootCommand()
+	root.SetArgs(args)
+	root.SetIn(strings.NewReader(stdin))
+	root.SetOut(&out)
+	root.SetErr(&out)
+
+	err := root.Execute()
+	return out.String(), err
+}
+
+func TestConfigPrint(t *testing.T) {
+	_, configFile := writeConfig(t)
+
+	out, err := run(t, "", "--config", configFile, "--db-driver", "memory", "config", "print")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "driver: memory")
+	assert.Contains(t, out, "token: '[REDACTED]'")
+	assert.Contains(t, out, "password: '[REDACTED]'")
+	assert.NotContains(t, out, "admin-token-0123456789")
+	assert.NotContains(t, out, "P@ssword1234")
+}
+
+func TestShippedConfigWithoutSecrets(t *testing.T) {
+	t.Setenv("APP_ENV", "")
+
+	out, err := run(t, "", "--config-dir", "..", "--db-driver", "memory", "config", "print")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "env: development")
+}
+
+func TestMigrateCommand(t *testing.T) {
+	_, configFile := writeConfig(t)
+
+	out, err := run(t, "", "--config", configFile, "migrate", "status")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "0001_create_customers")
+	assert.Contains(t, out, "pending")
+
+	out, err = run(t, "", "--config", configFile, "migrate", "up")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "applied  0001_create_customers")
+
+	out, err = run(t, "", "--config", configFile, "migrate", "down", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(out, "reverted"))
+
+	_, err = run(t, "", "--config", configFile, "migrate", "down", "zero")
+	assert.Error(t, err)
+}
+
+func TestSeedAndTransferCommands(t *testing.T) {
+	dir, configFile := writeConfig(t)
+
+	fixtures := filepath.Join(dir, "customers.yml")
+	require.NoError(t, os.WriteFile(fixtures, []byte("customers:\n  - name: John Doe\n    age: 23\n  - name: Jane Smith\n    age: 44\n"), 0o600))
+
+	out, err := run(t, "", "--config", configFile, "seed", "--file", fixtures)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "seeded 2 customers: 2 created")
+
+	out, err = run(t, "full_name,years\nBob,30\nBad,999\n", "--config", configFile, "customers", "import", "--map", "full_name=name,years=age")
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"imported": 1`)
+	assert.Contains(t, out, `"failed": 1`)
+
+	out, err = run(t, "", "--config", configFile, "customers", "export", "--min-age", "24")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out, "id,name,age,created_at,updated_at\n"))
+	assert.Contains(t, out, "Jane Smith")
+	assert.Contains(t, out, "Bob")
+	assert.NotContains(t, out, "John Doe")
+
+	output := filepath.Join(dir, "customers.ndjson")
+	_, err = run(t, "", "--config", configFile, "customers", "export", "--output", output)
+	assert.NoError(t, err)
+	exported, _ := os.ReadFile(output)
+	assert.Equal(t, 3, strings.Count(string(exported), "\n"))
+	assert.True(t, strings.HasPrefix(string(exported), "{"))
+}
+
+func TestSeedInvalidFixture(t *testing.T) {
+	dir, configFile := writeConfig(t)
+
+	fixtures := filepath.Join(dir, "customers.json")
+	require.NoError(t, os.WriteFile(fixtures, []byte(`{"customers": [{"name": "", "age": 20}]}`), 0o600))
+
+	_, err := run(t, "", "--config", configFile, "seed", "--file", fixtures)
+	assert.ErrorContains(t, err, "customer 1")
+}
+
+func TestSeedFixtureSet(t *testing.T) {
+	dir, configFile := writeConfig(t)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "fixtures", "base"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "fixtures", "staging"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fixtures", "base", "customers.yml"), []byte("customers:\n  - name: John Doe\n    age: 23\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fixtures", "staging", "customers.json"), []byte(`{"customers": [{"name": "John Doe", "age": 24}, {"name": "Jane Smith", "age": 44}]}`), 0o600))
+
+	args := []string{"--config", configFile, "seed", "--dir", filepath.Join(dir, "fixtures"), "--env", "staging"}
+
+	out, err := run(t, "", args...)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "seeded 2 customers: 2 created, 0 updated, 0 unchanged")
+
+	// seeding again is a no-op
+	out, err = run(t, "", args...)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "seeded 2 customers: 0 created, 0 updated, 2 unchanged")
+
+	out, err = run(t, "", append(args, "--generate", "50")...)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "seeded 52 customers: 50 created, 0 updated, 2 unchanged")
+
+	out, err = run(t, "", append(args, "--generate", "50")...)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "0 created, 0 updated, 52 unchanged")
+
+	out, err = run(t, "", "--config", configFile, "customers", "export")
+	assert.NoError(t, err)
+	assert.Equal(t, 53, strings.Count(out, "\n"))
+	assert.Contains(t, out, "John Doe,24")
+}
+
+func TestServeShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	cfg := &config.Config{
+		Server:     config.ServerConfig{Host: "127.0.0.1", Port: port, ShutdownTimeout: 5 * time.Second},
+		Header:     config.HeaderConfig{CORS: []string{"*"}},
+		Admin:      config.AdminConfig{Token: "admin-token-0123456789"},
+		Pagination: config.PaginationConfig{CursorSecret: "cursor-secret-0123456789"},
+		Database:   config.DatabaseConfig{Driver: config.DriverMemory, AutoMigrate: true},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, cfg) }()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/ping", port)
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(url)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// probes need no Origin header
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/health/ready", port))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("serve did not return after shutdown")
+	}
+
+	_, err = http.Get(url)
+	assert.Error(t, err, "the listener is closed")
+
+	sqlDB, err := config.Db.DB()
+	require.NoError(t, err)
+	assert.Error(t, sqlDB.Ping(), "the database is closed")
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"itmx_test/config"
+	"itmx_test/seed"
+	"itmx_test/service/customer/repository"
+	"itmx_test/service/customer/usecase"
+	"itmx_test/service/entity"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+func newSeedCommand(cfg *config.Config) *cobra.Command {
+	var (
+		files    []string
+		env      string
+		generate int
+		randSeed int64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Upsert customer fixtures, by name, from files or the environment's fixture set",
+		Long: `Upsert customer fixtures using the name as natural key, so seeding twice creates nothing.
+
+Without --file the fixture set of the environment is seeded: the files of <dir>/base, then
+the ones of <dir>/<env>. --generate adds synthetic customers for load testing.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if env == "" {
+				env = cfg.Env
+			}
+
+			var fixtures []seed.CustomerFixture
+			var err error
+			if len(files) > 0 {
+				fixtures, err = seed.LoadFiles(files...)
+			} else {
+				fixtures, err = seed.LoadSet(cfg.Seed.Dir, env)
+			}
+			if err != nil {
+				return err
+			}
+			fixtures = seed.Dedupe(append(fixtures, seed.Generate(generate, randSeed)...))
+
+			db, err := config.InitDB(cfg.Database)
+			if err != nil {
+				return err
+			}
+
+			report, err := seedCustomers(cliContext(cmd), db, fixtures)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "seeded %d customers: %d created, %d updated, %d unchanged\n",
+				len(fixtures), report.Created, report.Updated, report.Unchanged)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringSliceVarP(&files, "file", "f", nil, "fixture files to seed instead of the environment's set")
+	flags.String("dir", "", "fixture set directory (default fixtures)")
+	flags.StringVar(&env, "env", "", "fixture set to seed on top of base (default the APP_ENV environment)")
+	flags.IntVar(&generate, "generate", 0, "number of synthetic customers to add")
+	flags.Int64Var(&randSeed, "rand-seed", 1, "seed of the synthetic customers, the same seed yields the same customers")
+	bindFlag(flags.Lookup("dir"), "seed.dir")
+
+	return cmd
+}
+
+func seedCustomers(ctx context.Context, db *gorm.DB, fixtures []seed.CustomerFixture) (*entity.SeedReport, error) {
+	cu := usecase.NewCustomerUsecase(repository.NewCustomerRepository(db))
+	return cu.SeedCustomers(ctx, seed.Customers(fixtures))
+}
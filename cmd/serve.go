@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"itmx_test/config"
+	"itmx_test/health"
+	"itmx_test/metrics"
+	"itmx_test/middleware"
+	"itmx_test/problem"
+	"itmx_test/seed"
+	"itmx_test/service/customer/delivery"
+	"itmx_test/service/customer/repository"
+	"itmx_test/service/customer/usecase"
+	"itmx_test/service/entity"
+	idempotencyRepository "itmx_test/service/idempotency/repository"
+	webhookDelivery "itmx_test/service/webhook/delivery"
+	webhookRepository "itmx_test/service/webhook/repository"
+	webhookUsecase "itmx_test/service/webhook/usecase"
+	"itmx_test/tracing"
+	"itmx_test/util"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// seedActor is the audit actor of the fixtures seeded on startup
+const seedActor = "seed"
+
+func newServeCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Start the HTTP server and the webhook dispatcher",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return serve(ctx, cfg)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.String("host", "", "address to listen on")
+	flags.Int("port", 0, "port to listen on")
+	flags.Bool("auto-migrate", false, "apply pending migrations before serving")
+	bindFlag(flags.Lookup("host"), "server.host")
+	bindFlag(flags.Lookup("port"), "server.port")
+	bindFlag(flags.Lookup("auto-migrate"), "database.auto_migrate")
+
+	return cmd
+}
+
+// serve runs the HTTP server and the webhook dispatcher until ctx is cancelled, then shuts down
+// gracefully: the listener is closed, in-flight requests are drained, the dispatcher finishes
+// the delivery it is making and the database is closed, all within server.shutdown_timeout.
+func serve(ctx context.Context, cfg *config.Config) error {
+	if err := cfg.ValidateServe(); err != nil {
+		return err
+	}
+	if cfg.Admin.Token == "" {
+		logrus.Warn("admin.token is not set, admin access is disabled")
+	}
+	if cfg.Pagination.CursorSecret == "" {
+		logrus.Warn("pagination.cursor_secret is not set, cursor pagination requests fail")
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
+	if err != nil {
+		return fmt.Errorf("set up tracing: %w", err)
+	}
+	// set up first, so the spans of the whole shutdown are flushed last
+	defer func() {
+		flushCtx, cancel := shutdownContext(cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logrus.Errorf("flush traces: %v", err)
+		}
+	}()
+
+	dbConn, err := config.InitDB(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("initialize database: %w", err)
+	}
+	defer func() {
+		if err := config.Close(dbConn); err != nil {
+			logrus.Errorf("close database: %v", err)
+		}
+	}()
+
+	if err := instrumentDB(dbConn, cfg.Database.Driver); err != nil {
+		return fmt.Errorf("instrument database: %w", err)
+	}
+
+	// fixtures are upserted by name, so seeding on every start never duplicates customers
+	if cfg.Seed.OnStart {
+		fixtures, err := seed.LoadSet(cfg.Seed.Dir, cfg.Env)
+		if err != nil {
+			return fmt.Errorf("load fixtures: %w", err)
+		}
+
+		ctx := entity.ContextWithAuditMeta(context.Background(), entity.AuditMeta{Actor: seedActor})
+		report, err := seedCustomers(ctx, dbConn, fixtures)
+		if err != nil {
+			return fmt.Errorf("seed customers: %w", err)
+		}
+		logrus.Infof("seeded %d customers: %d created, %d updated, %d unchanged",
+			len(fixtures), report.Created, report.Updated, report.Unchanged)
+	}
+
+	bodyLimit := cfg.Server.BodyLimitMB << 20
+	f := fiber.New(fiber.Config{
+		JSONEncoder:  json.Marshal,
+		JSONDecoder:  json.Unmarshal,
+		Prefork:      false,
+		ServerHeader: "Fiber",
+		// stream request bodies so large customer imports are not buffered in memory, the
+		// other routes are held to the body limit by middleware.BodyLimitMiddleware
+		StreamRequestBody: true,
+		BodyLimit:         bodyLimit,
+		// every failure is answered with an application/problem+json document
+		ErrorHandler: middleware.ErrorHandler,
+	})
+
+	// every request is measured, including the ones CORS rejects
+	f.Use(metrics.Middleware())
+
+	// probes and the scrape come first, they send no Origin and must not fill the access log
+	metrics.NewHandler(f)
+	checkers := []health.Checker{health.DatabaseChecker(dbConn), health.MigrationChecker(dbConn)}
+	if path := cfg.Database.SQLitePath(); path != "" {
+		checkers = append(checkers, health.DiskSpaceChecker(path, uint64(cfg.Health.MinFreeDiskMB)<<20))
+	}
+	health.NewHandler(f, health.New(health.Config{
+		CacheTTL: cfg.Health.CacheTTL,
+		Timeout:  cfg.Health.Timeout,
+	}, checkers...))
+
+	// traces every request after the probes, before the timeout middleware derives its context
+	f.Use(tracing.Middleware())
+
+	// every request from here on has a request ID and an access log line, the rejected ones too
+	f.Use(middleware.RequestIDMiddleware())
+	f.Use(middleware.AccessLogMiddleware())
+	f.Use(middleware.RecoverMiddleware())
+
+	corsAllowList := cfg.Header.CORS
+	middL := middleware.CORSMiddleware(corsAllowList)
+	f.Use(middL)
+
+	f.Use(middleware.AdminMiddleware(cfg.Admin.Token))
+
+	f.Use(middleware.BodyLimitMiddleware(bodyLimit, "POST /customers/import"))
+
+	f.Use(middleware.TimeoutMiddleware(timeoutConfig(cfg.Timeout)))
+
+	util.SetCursorSecret(cfg.Pagination.CursorSecret)
+
+	// retried customer creations with the same Idempotency-Key replay the first response
+	idempotencyRepo := idempotencyRepository.NewIdempotencyRepository(dbConn)
+	idempotency := middleware.IdempotencyMiddleware(idempotencyRepo, cfg.Idempotency.TTL, cfg.Idempotency.Lease)
+	f.Post("/customers", idempotency)
+	f.Post("/customers/bulk", idempotency)
+
+	customerRepo := repository.NewCustomerRepository(dbConn)
+
+	customerUsecase := usecase.NewTracedCustomerUsecase(usecase.NewCustomerUsecase(customerRepo))
+
+	delivery.NewCustomerHandler(f, customerUsecase)
+
+	webhookRepo := webhookRepository.NewWebhookRepository(dbConn)
+
+	webhookUsecases := webhookUsecase.NewWebhookUsecase(webhookRepo)
+
+	webhookDelivery.NewWebhookHandler(f, webhookUsecases)
+
+	// the catalogue of the error codes the handlers above answer with
+	problem.NewHandler(f)
+
+	// deliver outbox events to webhook subscribers in the background
+	dispatcher := webhookUsecase.NewWebhookDispatcher(webhookRepo, webhookUsecase.DispatcherConfig{
+		PollInterval: cfg.Webhook.PollInterval,
+		BatchSize:    cfg.Webhook.BatchSize,
+		MaxAttempts:  cfg.Webhook.MaxAttempts,
+		BaseBackoff:  cfg.Webhook.BaseBackoff,
+		MaxBackoff:   cfg.Webhook.MaxBackoff,
+		Timeout:      cfg.Webhook.Timeout,
+	})
+	dispatcherCtx, stopDispatcher := context.WithCancel(context.Background())
+	defer stopDispatcher()
+	dispatcherDone := make(chan struct{})
+	go func() {
+		defer close(dispatcherDone)
+		dispatcher.Run(dispatcherCtx)
+	}()
+
+	f.Get("/ping", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "pong",
+		})
+	})
+
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- f.Listen(cfg.Server.Addr())
+	}()
+
+	select {
+	case err := <-listenErr:
+		// the listener failed, there are no requests to drain
+		stopDispatcher()
+		<-dispatcherDone
+		return err
+	case <-ctx.Done():
+	}
+
+	logrus.Info("shutting down, draining in-flight requests")
+	shutdownCtx, cancel := shutdownContext(cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err = f.ShutdownWithContext(shutdownCtx); err != nil {
+		err = fmt.Errorf("drain in-flight requests: %w", err)
+	}
+
+	stopDispatcher()
+	select {
+	case <-dispatcherDone:
+	case <-shutdownCtx.Done():
+		logrus.Warn("webhook dispatcher did not stop before the shutdown timeout")
+	}
+
+	return err
+}
+
+// instrumentDB times and traces the queries of db and exposes its connection pool statistics
+func instrumentDB(db *gorm.DB, name string) error {
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		return err
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return metrics.RegisterDBStats(sqlDB, name)
+}
+
+// shutdownContext bounds the shutdown by timeout, 0 leaves it unbounded
+func shutdownContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// timeoutConfig converts the timeout settings for middleware.TimeoutMiddleware
+func timeoutConfig(tc config.TimeoutConfig) middleware.TimeoutConfig {
+	routes := []middleware.RouteTimeout{}
+	for _, route := range tc.Routes {
+		routes = append(routes, middleware.RouteTimeout{Method: route.Method, Path: route.Path, Timeout: route.Timeout})
+	}
+	return middleware.TimeoutConfig{Default: tc.Default, Routes: routes}
+}
@@ -0,0 +1,401 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/spf13/viper"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+
+	// EnvPrefix prefixes the environment variables overriding config keys, ITMX_DATABASE_PASSWORD
+	// sets database.password. ITMX_DATABASE_PASSWORD_FILE reads it from a file instead.
+	EnvPrefix = "ITMX"
+
+	redacted = "[REDACTED]"
+)
+
+// envFileNames keeps the short file names of the well known environments
+var envFileNames = map[string]string{
+	EnvDevelopment: "dev",
+	EnvProduction:  "prod",
+}
+
+// Config is the whole application configuration. Fields tagged secret are redacted from dumps.
+type Config struct {
+	Env         string            `mapstructure:"env"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Header      HeaderConfig      `mapstructure:"header"`
+	Admin       AdminConfig       `mapstructure:"admin"`
+	Pagination  PaginationConfig  `mapstructure:"pagination"`
+	Timeout     TimeoutConfig     `mapstructure:"timeout"`
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+	Webhook     WebhookConfig     `mapstructure:"webhook"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Seed        SeedConfig        `mapstructure:"seed"`
+	Health      HealthConfig      `mapstructure:"health"`
+	Tracing     TracingConfig     `mapstructure:"tracing"`
+	Log         LogConfig         `mapstructure:"log"`
+}
+
+type ServerConfig struct {
+	Host string `mapstructure:"host"`
+	Port int    `mapstructure:"port" validate:"required,min=1,max=65535"`
+	// ShutdownTimeout bounds the draining of in-flight requests and background work on
+	// SIGTERM or SIGINT, 0 waits for them however long they take
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" validate:"min=0"`
+	// BodyLimitMB caps request bodies, the streamed customer import excepted, 0 disables it
+	BodyLimitMB int `mapstructure:"body_limit_mb" validate:"min=0"`
+}
+
+// Addr is the address the HTTP server listens on
+func (sc ServerConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", sc.Host, sc.Port)
+}
+
+type HeaderConfig struct {
+	Referer []string `mapstructure:"referer"`
+	CORS    []string `mapstructure:"cors"`
+}
+
+type AdminConfig struct {
+	// Token grants admin access, an empty token disables it
+	Token string `mapstructure:"token" validate:"omitempty,min=16" secret:"true"`
+}
+
+type PaginationConfig struct {
+	// CursorSecret signs the cursors of cursor pagination, only serve needs it
+	CursorSecret string `mapstructure:"cursor_secret" validate:"omitempty,min=16" secret:"true"`
+}
+
+// RouteTimeout overrides the default timeout for the routes under Path, see
+// middleware.RouteTimeout
+type RouteTimeout struct {
+	Method  string        `mapstructure:"method"`
+	Path    string        `mapstructure:"path"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+type TimeoutConfig struct {
+	Default time.Duration  `mapstructure:"default"`
+	Routes  []RouteTimeout `mapstructure:"routes"`
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration `mapstructure:"ttl" validate:"gt=0"`
+	// Lease reserves a key while its first request runs, a key left reserved by a crash is
+	// free again after it. Keep it above the timeout of the idempotent routes.
+	Lease time.Duration `mapstructure:"lease" validate:"gt=0"`
+}
+
+type WebhookConfig struct {
+	PollInterval time.Duration `mapstructure:"poll_interval" validate:"min=0"`
+	BatchSize    int           `mapstructure:"batch_size" validate:"min=0"`
+	MaxAttempts  int           `mapstructure:"max_attempts" validate:"min=0"`
+	BaseBackoff  time.Duration `mapstructure:"base_backoff" validate:"min=0"`
+	MaxBackoff   time.Duration `mapstructure:"max_backoff" validate:"min=0"`
+	Timeout      time.Duration `mapstructure:"timeout" validate:"min=0"`
+}
+
+type SeedConfig struct {
+	Dir     string `mapstructure:"dir"`
+	OnStart bool   `mapstructure:"on_start"`
+}
+
+type HealthConfig struct {
+	CacheTTL time.Duration `mapstructure:"cache_ttl" validate:"min=0"`
+	Timeout  time.Duration `mapstructure:"timeout" validate:"min=0"`
+	// MinFreeDiskMB fails readiness when the disk of the SQLite file has less space left
+	MinFreeDiskMB int `mapstructure:"min_free_disk_mb" validate:"min=0"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, stdout, file or otlp
+	Exporter    string  `mapstructure:"exporter" validate:"oneof=none stdout file otlp"`
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	File        string  `mapstructure:"file"`
+	SampleRatio float64 `mapstructure:"sample_ratio" validate:"min=0,max=1"`
+	ServiceName string  `mapstructure:"service_name" validate:"required"`
+}
+
+type LogConfig struct {
+	Level string `mapstructure:"level" validate:"oneof=panic fatal error warn info debug trace"`
+	// Format is json, one object per line, or text for reading in a terminal
+	Format string `mapstructure:"format" validate:"oneof=json text"`
+}
+
+// LoadOptions selects the config files to read. File, when set, is read alone instead of the
+// base file and the environment file of Dir.
+type LoadOptions struct {
+	Dir  string
+	Env  string
+	File string
+}
+
+// Load reads the configuration in layers, each overriding the one before: Dir/config.yml, the
+// environment file Dir/config.<env>.yml, then ITMX_* environment variables and ITMX_*_FILE
+// secret files. Flags bound to v override the files and variables, a secret file overrides
+// everything. The result is validated.
+func Load(v *viper.Viper, opts LoadOptions) (*Config, error) {
+	if opts.Env == "" {
+		opts.Env = EnvDevelopment
+	}
+
+	setDefaults(v)
+
+	if err := readFiles(v, opts); err != nil {
+		return nil, err
+	}
+
+	keys := configKeys(reflect.TypeOf(Config{}), "")
+
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	for _, key := range keys {
+		if err := v.BindEnv(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := readSecretFiles(v, keys); err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	// unknown keys are rejected, a misspelled key would otherwise be silently ignored
+	if err := v.UnmarshalExact(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	config.Env = opts.Env
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault(`server.port`, 3000)
+	v.SetDefault(`server.shutdown_timeout`, 20*time.Second)
+	v.SetDefault(`server.body_limit_mb`, 4)
+	v.SetDefault(`timeout.default`, 10*time.Second)
+	v.SetDefault(`idempotency.ttl`, 24*time.Hour)
+	v.SetDefault(`idempotency.lease`, time.Minute)
+	v.SetDefault(`database.driver`, DriverSQLite)
+	v.SetDefault(`database.slow_query_threshold`, 200*time.Millisecond)
+	v.SetDefault(`seed.dir`, "fixtures")
+	v.SetDefault(`health.cache_ttl`, 5*time.Second)
+	v.SetDefault(`health.timeout`, 2*time.Second)
+	v.SetDefault(`health.min_free_disk_mb`, 100)
+	v.SetDefault(`tracing.exporter`, "none")
+	v.SetDefault(`tracing.file`, "traces.jsonl")
+	v.SetDefault(`tracing.sample_ratio`, 1)
+	v.SetDefault(`tracing.service_name`, "itmx")
+	v.SetDefault(`log.level`, "info")
+	v.SetDefault(`log.format`, "json")
+}
+
+func readFiles(v *viper.Viper, opts LoadOptions) error {
+	if opts.File != "" {
+		v.SetConfigFile(opts.File)
+		if err := v.ReadInConfig(); err != nil {
+			return fmt.Errorf("read config file %s: %w", opts.File, err)
+		}
+		return nil
+	}
+
+	name, ok := envFileNames[opts.Env]
+	if !ok {
+		name = opts.Env
+	}
+	files := []string{
+		filepath.Join(opts.Dir, "config.yml"),
+		filepath.Join(opts.Dir, "config."+name+".yml"),
+	}
+
+	read := 0
+	for _, file := range files {
+		if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		v.SetConfigFile(file)
+		if err := v.MergeInConfig(); err != nil {
+			return fmt.Errorf("read config file %s: %w", file, err)
+		}
+		read++
+	}
+
+	if read == 0 {
+		return fmt.Errorf("no config file found, looked for %s", strings.Join(files, " and "))
+	}
+	return nil
+}
+
+// readSecretFiles sets every key whose ITMX_<KEY>_FILE variable names a file to the content of
+// that file, the way container secrets are mounted
+func readSecretFiles(v *viper.Viper, keys []string) error {
+	for _, key := range keys {
+		env := envName(key)
+		file, ok := os.LookupEnv(env + "_FILE")
+		if !ok {
+			continue
+		}
+		if _, ok := os.LookupEnv(env); ok {
+			return fmt.Errorf("invalid config: both %s and %s_FILE are set", env, env)
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("read secret file of %s: %w", key, err)
+		}
+		v.Set(key, strings.TrimSpace(string(content)))
+	}
+	return nil
+}
+
+func envName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// configKeys lists the dotted keys of every leaf field of t
+func configKeys(t reflect.Type, prefix string) []string {
+	keys := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+			keys = append(keys, configKeys(field.Type, key+".")...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+var validate = func() *validator.Validate {
+	v := validator.New()
+	// report fields by their config key
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("mapstructure")
+	})
+	return v
+}()
+
+// Validate checks every field and reports all the problems at once
+func (c *Config) Validate() error {
+	problems := []string{}
+
+	if err := validate.Struct(c); err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return err
+		}
+		for _, fieldError := range validationErrors {
+			key := strings.TrimPrefix(fieldError.Namespace(), "Config.")
+			problem := fmt.Sprintf("%s is %s", key, fieldError.Tag())
+			if fieldError.Param() != "" {
+				problem += " " + fieldError.Param()
+			}
+			problems = append(problems, problem)
+		}
+	}
+
+	if c.Idempotency.Lease >= c.Idempotency.TTL {
+		problems = append(problems, "idempotency.lease must be shorter than idempotency.ttl")
+	}
+	problems = append(problems, c.Database.problems()...)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ValidateServe checks the settings only the HTTP server needs. Production requires
+// pagination.cursor_secret, other environments may leave it out and then answer cursor
+// pagination requests with an error.
+func (c *Config) ValidateServe() error {
+	if c.Env == EnvProduction && c.Pagination.CursorSecret == "" {
+		return errors.New("invalid config: pagination.cursor_secret is required to serve in production")
+	}
+	return nil
+}
+
+func (dc DatabaseConfig) problems() []string {
+	problems := []string{}
+
+	switch dc.Driver {
+	case DriverSQLite, DriverMemory:
+	case DriverMySQL, DriverPostgres:
+		if dc.DSN == "" && dc.Host == "" {
+			problems = append(problems, "database.host is required by the "+dc.Driver+" driver unless database.dsn is set")
+		}
+		if dc.DSN == "" && dc.DBName == "" {
+			problems = append(problems, "database.dbname is required by the "+dc.Driver+" driver unless database.dsn is set")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("database.driver is one of %s, %s, %s or %s, not %q",
+			DriverSQLite, DriverMemory, DriverMySQL, DriverPostgres, dc.Driver))
+	}
+
+	if dc.MaxOpenConns < 0 || dc.MaxIdleConns < 0 || dc.ConnectRetries < 0 {
+		problems = append(problems, "database connection counts cannot be negative")
+	}
+
+	return problems
+}
+
+// Redacted returns the configuration as nested maps keyed like the config file, with secrets
+// replaced and durations written the way they are configured
+func (c *Config) Redacted() map[string]interface{} {
+	return redactStruct(reflect.ValueOf(*c))
+}
+
+func redactStruct(value reflect.Value) map[string]interface{} {
+	out := map[string]interface{}{}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key := field.Tag.Get("mapstructure")
+		out[key] = redactValue(value.Field(i), field.Tag.Get("secret") == "true")
+	}
+	return out
+}
+
+func redactValue(value reflect.Value, secret bool) interface{} {
+	if secret {
+		if value.IsZero() {
+			return ""
+		}
+		return redacted
+	}
+
+	if duration, ok := value.Interface().(time.Duration); ok {
+		return duration.String()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return redactStruct(value)
+	case reflect.Slice:
+		items := make([]interface{}, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			items = append(items, redactValue(value.Index(i), false))
+		}
+		return items
+	default:
+		return value.Interface()
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baseConfig = `server:
+  port: 3000
+admin:
+  token: base-admin-token-0123
+pagination:
+  cursor_secret: base-cursor-secret-0123
+webhook:
+  max_attempts: 8
+  base_backoff: 10s
+database:
+  driver: sqlite
+  path: base.sqlite
+  password: base-password
+`
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("environment file on top of base", func(t *testing.T) {
+		dir := writeConfigFiles(t, map[string]string{
+			"config.yml":      baseConfig,
+			"config.prod.yml": "server:\n  port: 8080\ndatabase:\n  path: prod.sqlite\n",
+		})
+
+		config, err := Load(viper.New(), LoadOptions{Dir: dir, Env: EnvProduction})
+		require.NoError(t, err)
+		assert.Equal(t, EnvProduction, config.Env)
+		assert.Equal(t, 8080, config.Server.Port)
+		assert.Equal(t, "prod.sqlite", config.Database.Path)
+		assert.Equal(t, "base-password", config.Database.Password)
+		assert.Equal(t, 10*time.Second, config.Webhook.BaseBackoff)
+		// defaults fill what no file sets
+		assert.Equal(t, 24*time.Hour, config.Idempotency.TTL)
+		assert.Equal(t, time.Minute, config.Idempotency.Lease)
+		assert.Equal(t, "fixtures", config.Seed.Dir)
+	})
+
+	t.Run("environment variables and secret files", func(t *testing.T) {
+		dir := writeConfigFiles(t, map[string]string{"config.yml": baseConfig})
+		secret := filepath.Join(dir, "db_password")
+		require.NoError(t, os.WriteFile(secret, []byte("from-secret-file\n"), 0o600))
+
+		t.Setenv("ITMX_SERVER_PORT", "9090")
+		t.Setenv("ITMX_HEADER_CORS", "https://a.example,https://b.example")
+		t.Setenv("ITMX_WEBHOOK_BASE_BACKOFF", "1m")
+		t.Setenv("ITMX_DATABASE_PASSWORD_FILE", secret)
+
+		config, err := Load(viper.New(), LoadOptions{Dir: dir})
+		require.NoError(t, err)
+		assert.Equal(t, EnvDevelopment, config.Env)
+		assert.Equal(t, 9090, config.Server.Port)
+		assert.Equal(t, []string{"https://a.example", "https://b.example"}, config.Header.CORS)
+		assert.Equal(t, time.Minute, config.Webhook.BaseBackoff)
+		assert.Equal(t, "from-secret-file", config.Database.Password)
+	})
+
+	t.Run("variable and secret file both set", func(t *testing.T) {
+		dir := writeConfigFiles(t, map[string]string{"config.yml": baseConfig})
+		t.Setenv("ITMX_ADMIN_TOKEN", "admin-token-from-env")
+		t.Setenv("ITMX_ADMIN_TOKEN_FILE", filepath.Join(dir, "token"))
+
+		_, err := Load(viper.New(), LoadOptions{Dir: dir})
+		assert.ErrorContains(t, err, "both ITMX_ADMIN_TOKEN and ITMX_ADMIN_TOKEN_FILE are set")
+	})
+
+	t.Run("single file", func(t *testing.T) {
+		dir := writeConfigFiles(t, map[string]string{
+			"config.yml":   "server:\n  port: 1\n",
+			"explicit.yml": baseConfig,
+		})
+
+		config, err := Load(viper.New(), LoadOptions{Dir: dir, File: filepath.Join(dir, "explicit.yml")})
+		require.NoError(t, err)
+		assert.Equal(t, 3000, config.Server.Port)
+	})
+
+	t.Run("no config file", func(t *testing.T) {
+		_, err := Load(viper.New(), LoadOptions{Dir: t.TempDir()})
+		assert.ErrorContains(t, err, "no config file found")
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		dir := writeConfigFiles(t, map[string]string{"config.yml": baseConfig + "  pasword: typo\n"})
+
+		_, err := Load(viper.New(), LoadOptions{Dir: dir})
+		assert.ErrorContains(t, err, "pasword")
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		dir := writeConfigFiles(t, map[string]string{
+			"config.yml": "server:\n  port: 70000\nadmin:\n  token: short\ndatabase:\n  driver: mysql\n",
+		})
+
+		_, err := Load(viper.New(), LoadOptions{Dir: dir})
+		assert.EqualError(t, err, "invalid config: server.port is max 65535; admin.token is min 16; "+
+			"database.host is required by the mysql driver unless database.dsn is set; "+
+			"database.dbname is required by the mysql driver unless database.dsn is set")
+	})
+
+	t.Run("secrets are optional", func(t *testing.T) {
+		dir := writeConfigFiles(t, map[string]string{"config.yml": "database:\n  driver: sqlite\n"})
+
+		config, err := Load(viper.New(), LoadOptions{Dir: dir})
+		require.NoError(t, err)
+		assert.Empty(t, config.Admin.Token)
+		assert.Empty(t, config.Pagination.CursorSecret)
+	})
+
+	t.Run("idempotency lease not shorter than the ttl", func(t *testing.T) {
+		dir := writeConfigFiles(t, map[string]string{"config.yml": baseConfig + "idempotency:\n  ttl: 1m\n  lease: 1m\n"})
+
+		_, err := Load(viper.New(), LoadOptions{Dir: dir})
+		assert.EqualError(t, err, "invalid config: idempotency.lease must be shorter than idempotency.ttl")
+	})
+}
+
+func TestValidateServe(t *testing.T) {
+	assert.NoError(t, (&Config{Env: EnvDevelopment}).ValidateServe())
+	assert.EqualError(t, (&Config{Env: EnvProduction}).ValidateServe(),
+		"invalid config: pagination.cursor_secret is required to serve in production")
+	assert.NoError(t, (&Config{
+		Env:        EnvProduction,
+		Pagination: PaginationConfig{CursorSecret: "cursor-secret-0123456789"},
+	}).ValidateServe())
+}
+
+func TestRedacted(t *testing.T) {
+	config := &Config{
+		Server:     ServerConfig{Host: "localhost", Port: 3000},
+		Admin:      AdminConfig{Token: "admin-token-0123456789"},
+		Pagination: PaginationConfig{},
+		Webhook:    WebhookConfig{BaseBackoff: 10 * time.Second},
+		Database:   DatabaseConfig{Driver: DriverMySQL, Password: "P@ssword1234"},
+	}
+
+	dump := config.Redacted()
+	assert.Equal(t, map[string]interface{}{"host": "localhost", "port": 3000, "shutdown_timeout": "0s", "body_limit_mb": 0}, dump["server"])
+	assert.Equal(t, "[REDACTED]", dump["admin"].(map[string]interface{})["token"])
+	// an unset secret shows it is missing
+	assert.Equal(t, "", dump["pagination"].(map[string]interface{})["cursor_secret"])
+	assert.Equal(t, "10s", dump["webhook"].(map[string]interface{})["base_backoff"])
+
+	database := dump["database"].(map[string]interface{})
+	assert.Equal(t, "[REDACTED]", database["password"])
+	assert.Equal(t, DriverMySQL, database["driver"])
+}
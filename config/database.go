@@ -1,27 +1,267 @@
 package config
 
 import (
+	"context"
+	"fmt"
+	"net"
 	"net/url"
+	"strconv"
+	"time"
 
-	"itmx_test/service/entity"
+	"itmx_test/logging"
+	"itmx_test/migration"
 
 	"github.com/glebarez/sqlite"
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+const (
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+)
+
+// DatabaseConfig is the database block of the config file. DSN, when set, is used as is
+// instead of the one built from the other fields.
+type DatabaseConfig struct {
+	Driver   string `mapstructure:"driver"`
+	DSN      string `mapstructure:"dsn" secret:"true"`
+	Path     string `mapstructure:"path"`
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password" secret:"true"`
+	DBName   string `mapstructure:"dbname"`
+	SSLMode  string `mapstructure:"sslmode"`
+
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
+
+	ConnectRetries int           `mapstructure:"connect_retries"`
+	RetryBackoff   time.Duration `mapstructure:"retry_backoff"`
+
+	// AutoMigrate applies pending migrations on startup instead of refusing to start
+	AutoMigrate bool `mapstructure:"auto_migrate"`
+
+	// SlowQueryThreshold logs the queries taking longer at warn level, 0 disables it
+	SlowQueryThreshold time.Duration `mapstructure:"slow_query_threshold"`
+}
+
 var Db *gorm.DB
 
-func InitDB() *gorm.DB {
-	val := url.Values{}
-	val.Add("parseTime", "1")
-	val.Add("loc", "Local")
-	Db, err := gorm.Open(sqlite.Open("itmx.sqlite"), &gorm.Config{})
+// InitDB connects to the configured database, retrying with exponential backoff while it is
+// unreachable, then migrates the schema when auto_migrate is set or checks it is up to date
+func InitDB(config DatabaseConfig) (*gorm.DB, error) {
+	db, err := Connect(config)
 	if err != nil {
-		panic("Failed to connect to database")
+		return nil, err
 	}
-	Db.AutoMigrate(
-		entity.Customer{},
-	)
 
-	return Db
+	if config.AutoMigrate {
+		err = Migrate(db)
+	} else {
+		err = CheckMigrations(db)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	Db = db
+	return db, nil
+}
+
+// Connect opens the database and applies the connection pool settings
+func Connect(config DatabaseConfig) (*gorm.DB, error) {
+	dialector, err := config.Dialector()
+	if err != nil {
+		return nil, err
+	}
+
+	retries := config.ConnectRetries
+	if retries < 0 {
+		retries = 0
+	}
+	backoff := config.RetryBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	var db *gorm.DB
+	for attempt := 0; ; attempt++ {
+		db, err = gorm.Open(dialector, &gorm.Config{Logger: logging.NewGormLogger(config.SlowQueryThreshold)})
+		if err == nil {
+			break
+		}
+		if attempt >= retries {
+			return nil, fmt.Errorf("connect to %s database: %w", config.Driver, err)
+		}
+
+		logrus.Warnf("connect to %s database failed, retrying in %s: %v", config.Driver, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Driver == DriverMemory {
+		// every connection to ":memory:" opens its own empty database, so the pool holds a
+		// single connection and never closes it, whatever the pool settings say
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+		return db, nil
+	}
+
+	if config.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	if config.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
+
+	return db, nil
+}
+
+// Close closes the connection pool of db, waiting for the queries in progress
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Dialector returns the gorm dialector of the configured driver
+func (dc DatabaseConfig) Dialector() (gorm.Dialector, error) {
+	dsn, err := dc.BuildDSN()
+	if err != nil {
+		return nil, err
+	}
+
+	switch dc.Driver {
+	case DriverSQLite, DriverMemory, "":
+		return sqlite.Open(dsn), nil
+	case DriverMySQL:
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		return postgres.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", dc.Driver)
+	}
+}
+
+// SQLitePath is the database file of the sqlite driver, empty for the other drivers or when
+// the DSN is given as is
+func (dc DatabaseConfig) SQLitePath() string {
+	if dc.DSN != "" || (dc.Driver != DriverSQLite && dc.Driver != "") {
+		return ""
+	}
+	if dc.Path == "" {
+		return "itmx.sqlite"
+	}
+	return dc.Path
+}
+
+// BuildDSN builds the data source name of the configured driver
+func (dc DatabaseConfig) BuildDSN() (string, error) {
+	if dc.DSN != "" {
+		return dc.DSN, nil
+	}
+
+	switch dc.Driver {
+	case DriverSQLite, "":
+		// wait for a locked database instead of failing right away
+		return dc.SQLitePath() + "?_pragma=busy_timeout(5000)", nil
+
+	case DriverMemory:
+		return ":memory:", nil
+
+	case DriverMySQL:
+		mysqlConfig := mysqlDriver.NewConfig()
+		mysqlConfig.User = dc.Username
+		mysqlConfig.Passwd = dc.Password
+		mysqlConfig.Net = "tcp"
+		mysqlConfig.Addr = net.JoinHostPort(dc.Host, strconv.Itoa(dc.portOr(3306)))
+		mysqlConfig.DBName = dc.DBName
+		mysqlConfig.ParseTime = true
+		mysqlConfig.Loc = time.Local
+		mysqlConfig.Params = map[string]string{"charset": "utf8mb4"}
+		return mysqlConfig.FormatDSN(), nil
+
+	case DriverPostgres:
+		sslMode := dc.SSLMode
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+		val := url.Values{}
+		val.Add("sslmode", sslMode)
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(dc.Username, dc.Password),
+			Host:     net.JoinHostPort(dc.Host, strconv.Itoa(dc.portOr(5432))),
+			Path:     "/" + dc.DBName,
+			RawQuery: val.Encode(),
+		}
+		return dsn.String(), nil
+
+	default:
+		return "", fmt.Errorf("unsupported database driver %q", dc.Driver)
+	}
+}
+
+func (dc DatabaseConfig) portOr(port int) int {
+	if dc.Port != 0 {
+		return dc.Port
+	}
+	return port
+}
+
+// Migrate applies every pending schema migration
+func Migrate(db *gorm.DB) error {
+	migrator, err := migration.New(db)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(context.Background())
+	for _, m := range applied {
+		logrus.Infof("applied migration %04d_%s", m.Version, m.Name)
+	}
+	return err
+}
+
+// CheckMigrations fails when the schema is behind the migrations of this build, so the
+// server never runs against a database that has not been migrated
+func CheckMigrations(db *gorm.DB) error {
+	migrator, err := migration.New(db)
+	if err != nil {
+		return err
+	}
+
+	pending, err := migrator.Pending(context.Background())
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migrations, starting with %04d_%s: run `migrate up` or enable database.auto_migrate",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
 }
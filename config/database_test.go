@@ -0,0 +1,136 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"itmx_test/service/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDSN(t *testing.T) {
+	tests := []struct {
+		name   string
+		config DatabaseConfig
+		dsn    string
+	}{
+		{"sqlite default", DatabaseConfig{}, "itmx.sqlite?_pragma=busy_timeout(5000)"},
+		{"sqlite file", DatabaseConfig{Driver: DriverSQLite, Path: "/data/app.db"}, "/data/app.db?_pragma=busy_timeout(5000)"},
+		{"memory", DatabaseConfig{Driver: DriverMemory}, ":memory:"},
+		{
+			"mysql",
+			DatabaseConfig{Driver: DriverMySQL, Host: "db", Username: "root", Password: "P@ss/word", DBName: "itmx"},
+			"root:P@ss/word@tcp(db:3306)/itmx?loc=Local&parseTime=true&charset=utf8mb4",
+		},
+		{
+			"postgres",
+			DatabaseConfig{Driver: DriverPostgres, Host: "db", Port: 6543, Username: "app", Password: "p@ss word", DBName: "itmx", SSLMode: "require"},
+			"postgres://app:p%40ss%20word@db:6543/itmx?sslmode=require",
+		},
+		{"explicit dsn", DatabaseConfig{Driver: DriverMySQL, DSN: "custom"}, "custom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dsn, err := tt.config.BuildDSN()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.dsn, dsn)
+		})
+	}
+
+	t.Run("unsupported driver", func(t *testing.T) {
+		_, err := DatabaseConfig{Driver: "oracle"}.BuildDSN()
+		assert.Error(t, err)
+	})
+}
+
+func TestConnectRetry(t *testing.T) {
+	config := DatabaseConfig{
+		Driver:         DriverMySQL,
+		Host:           "127.0.0.1",
+		Port:           1,
+		ConnectRetries: 2,
+		RetryBackoff:   10 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err := Connect(config)
+	assert.Error(t, err)
+	// two retries wait 10ms then 20ms
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+}
+
+func TestInitDB(t *testing.T) {
+	t.Run("sqlite file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "itmx.sqlite")
+		db, err := InitDB(DatabaseConfig{Driver: DriverSQLite, Path: path, MaxOpenConns: 4, AutoMigrate: true})
+		assert.NoError(t, err)
+		assert.FileExists(t, path)
+
+		sqlDB, _ := db.DB()
+		defer sqlDB.Close()
+		assert.Equal(t, 4, sqlDB.Stats().MaxOpenConnections)
+
+		// migrating again is a no-op
+		assert.NoError(t, Migrate(db))
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		db, err := InitDB(DatabaseConfig{Driver: DriverMemory, MaxOpenConns: 10, AutoMigrate: true})
+		assert.NoError(t, err)
+
+		sqlDB, _ := db.DB()
+		defer sqlDB.Close()
+		assert.Equal(t, 1, sqlDB.Stats().MaxOpenConnections)
+
+		assert.True(t, db.Migrator().HasTable("customer_search"))
+
+		audit := &entity.CustomerAudit{CustomerID: "1", Operation: entity.AuditCreate}
+		assert.NoError(t, db.Create(audit).Error)
+		assert.Error(t, db.Model(audit).Update("actor", "mallory").Error)
+		assert.Error(t, db.Delete(audit).Error)
+	})
+
+	t.Run("memory survives the pool settings", func(t *testing.T) {
+		db, err := InitDB(DatabaseConfig{
+			Driver:          DriverMemory,
+			MaxIdleConns:    -1,
+			ConnMaxLifetime: time.Millisecond,
+			ConnMaxIdleTime: time.Millisecond,
+			AutoMigrate:     true,
+		})
+		assert.NoError(t, err)
+
+		sqlDB, _ := db.DB()
+		defer sqlDB.Close()
+
+		assert.NoError(t, db.Create(&entity.Customer{ID: "1", Name: "John Doe", Age: 23}).Error)
+
+		// a recycled connection would open an empty database without the schema
+		time.Sleep(20 * time.Millisecond)
+		var count int64
+		assert.NoError(t, db.Model(&entity.Customer{}).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+
+		stats := sqlDB.Stats()
+		assert.Zero(t, stats.MaxIdleClosed+stats.MaxIdleTimeClosed+stats.MaxLifetimeClosed)
+	})
+
+	t.Run("pending migrations", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "itmx.sqlite")
+		_, err := InitDB(DatabaseConfig{Driver: DriverSQLite, Path: path})
+		assert.ErrorContains(t, err, "pending migrations")
+
+		db, err := InitDB(DatabaseConfig{Driver: DriverSQLite, Path: path, AutoMigrate: true})
+		assert.NoError(t, err)
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+
+		db, err = InitDB(DatabaseConfig{Driver: DriverSQLite, Path: path})
+		assert.NoError(t, err)
+		sqlDB, _ = db.DB()
+		sqlDB.Close()
+	})
+}
@@ -1,119 +1,161 @@
 package domain
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
-
-	"github.com/sirupsen/logrus"
+	"sort"
 )
 
+// Error is an error a client can act on. Code is stable and part of the API, Status is the
+// HTTP status it is answered with and Message is safe to show to the client. Wrap it with
+// fmt.Errorf("...: %w", err) to add context for the logs.
+type Error struct {
+	Code    string `json:"code"`
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// FieldError is an Error caused by the value of one field, such as a unique value already
+// taken. Cause is the error it was made from, kept for the logs.
+type FieldError struct {
+	Err   *Error
+	Field string
+	Cause error
+}
+
+func (e *FieldError) Error() string {
+	msg := e.Field + ": " + e.Err.Message
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+func (e *FieldError) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.Cause}
+}
+
+// registry keeps every Error by code, it is the catalogue served at GET /errors
+var registry = map[string]*Error{}
+
+// NewError declares an Error. Codes are unique, declaring one twice panics.
+func NewError(code string, status int, message string) *Error {
+	if _, ok := registry[code]; ok {
+		panic(fmt.Sprintf("domain error %q declared twice", code))
+	}
+	err := &Error{Code: code, Status: status, Message: message}
+	registry[code] = err
+	return err
+}
+
+// Errors is the catalogue of declared errors, by status then code
+func Errors() []*Error {
+	errs := make([]*Error, 0, len(registry))
+	for _, err := range registry {
+		errs = append(errs, err)
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].Status != errs[j].Status {
+			return errs[i].Status < errs[j].Status
+		}
+		return errs[i].Code < errs[j].Code
+	})
+	return errs
+}
+
 var (
 	// ErrInternalServerError will throw if any the Internal Server Error happen
-	ErrInternalServerError = errors.New("internal Server Error")
+	ErrInternalServerError = NewError("internal_error", http.StatusInternalServerError, "internal Server Error")
 	// ErrNotFound will throw if the requested item is not exists
-	ErrNotFound = errors.New("your requested Item is not found")
+	ErrNotFound = NewError("not_found", http.StatusNotFound, "your requested Item is not found")
 	// ErrConflict will throw if the current action already exists
-	ErrConflict = errors.New("your Item already exist")
+	ErrConflict = NewError("conflict", http.StatusConflict, "your Item already exist")
 	// ErrBadParamInput will throw if the given request-body or params is not valid
-	ErrBadParamInput = errors.New("given Param is not valid")
+	ErrBadParamInput = NewError("bad_param_input", http.StatusBadRequest, "given Param is not valid")
 
 	// 400 BadRequest
-	ErrUploadLimit          = errors.New("upload limit reached")
-	ErrVoteLimit            = errors.New("vote limit reached")
-	ErrInvalidUserID        = errors.New("invalid userID")
-	ErrIsNotWalkIn          = errors.New("this user is not walk-in register")
-	ErrIsNotPass            = errors.New("this user this user criterion is not pass")
-	ErrInvalidVideoID       = errors.New("invalid videoID")
-	ErrVdoAndUserIDNotMatch = errors.New("videoID and userID is not match")
-	ErrInValidVdoUrl        = errors.New("video url is invalid")
-	ErrInvalidRegistType    = errors.New("invalid register type")
+	ErrUploadLimit           = NewError("upload_limit_reached", http.StatusBadRequest, "upload limit reached")
+	ErrVoteLimit             = NewError("vote_limit_reached", http.StatusBadRequest, "vote limit reached")
+	ErrInvalidUserID         = NewError("invalid_user_id", http.StatusBadRequest, "invalid userID")
+	ErrIsNotWalkIn           = NewError("not_walk_in", http.StatusBadRequest, "this user is not walk-in register")
+	ErrIsNotPass             = NewError("criterion_not_passed", http.StatusBadRequest, "this user this user criterion is not pass")
+	ErrInvalidVideoID        = NewError("invalid_video_id", http.StatusBadRequest, "invalid videoID")
+	ErrVdoAndUserIDNotMatch  = NewError("video_user_mismatch", http.StatusBadRequest, "videoID and userID is not match")
+	ErrInValidVdoUrl         = NewError("invalid_video_url", http.StatusBadRequest, "video url is invalid")
+	ErrInvalidRegistType     = NewError("invalid_register_type", http.StatusBadRequest, "invalid register type")
+	ErrInvalidCursor         = NewError("invalid_cursor", http.StatusBadRequest, "invalid cursor")
+	ErrUnsupportedFormat     = NewError("unsupported_format", http.StatusBadRequest, "unsupported file format")
+	ErrInvalidImportHeader   = NewError("invalid_import_header", http.StatusBadRequest, "import file header is invalid")
+	ErrInvalidIdempotencyKey = NewError("invalid_idempotency_key", http.StatusBadRequest, "idempotency key is invalid")
+	ErrMalformedBody         = NewError("malformed_body", http.StatusBadRequest, "request body could not be parsed")
+	ErrValidationFailed      = NewError("validation_failed", http.StatusBadRequest, "request failed validation")
 
 	// 401 StatusInvalidCredentials
-	ErrStatusInvalidCredentials = errors.New("invalid credentials")
+	ErrStatusInvalidCredentials = NewError("invalid_credentials", http.StatusUnauthorized, "invalid credentials")
 
 	// 403 StatusForbidden
-	ErrPermissionDenied = errors.New("permission denied")
-	ErrInvalidRecaptcha = errors.New("invalid recaptcha")
+	ErrPermissionDenied = NewError("permission_denied", http.StatusForbidden, "permission denied")
+	ErrInvalidRecaptcha = NewError("invalid_recaptcha", http.StatusForbidden, "invalid recaptcha")
+	ErrOriginNotAllowed = NewError("cors_not_allowed", http.StatusForbidden, "Not allowed by CORS")
 
 	// 404 StatusNotFound
-	ErrProvinceNotFound = errors.New("province is not found")
-	ErrUsernameNotFound = errors.New("username not found in the system")
-	ErrScoutNotFound    = errors.New("scout not found in the system")
+	ErrProvinceNotFound = NewError("province_not_found", http.StatusNotFound, "province is not found")
+	ErrUsernameNotFound = NewError("username_not_found", http.StatusNotFound, "username not found in the system")
+	ErrScoutNotFound    = NewError("scout_not_found", http.StatusNotFound, "scout not found in the system")
 
 	// 409 StatusConflict
-	ErrUsernameExist = errors.New("username already exists")
-	ErrEmailExist    = errors.New("email already exists")
-	ErrPhoneExist    = errors.New("phone number already exists")
-	ErrDupPhoneExist = errors.New("phone number and phone number backup are duplicate")
-	ErrIdNumberExist = errors.New("id card number already exists")
-	ErrScoreExist    = errors.New("score is already exists")
-	ErrCriState      = errors.New("criterion state is already updated")
-	ErrVdoUrlExist   = errors.New("video url is already exists")
+	ErrUsernameExist       = NewError("username_exists", http.StatusConflict, "username already exists")
+	ErrEmailExist          = NewError("email_exists", http.StatusConflict, "email already exists")
+	ErrPhoneExist          = NewError("phone_exists", http.StatusConflict, "phone number already exists")
+	ErrDupPhoneExist       = NewError("duplicate_phone", http.StatusConflict, "phone number and phone number backup are duplicate")
+	ErrIdNumberExist       = NewError("id_number_exists", http.StatusConflict, "id card number already exists")
+	ErrScoreExist          = NewError("score_exists", http.StatusConflict, "score is already exists")
+	ErrCriState            = NewError("criterion_state_updated", http.StatusConflict, "criterion state is already updated")
+	ErrVdoUrlExist         = NewError("video_url_exists", http.StatusConflict, "video url is already exists")
+	ErrIdempotencyInFlight = NewError("idempotency_in_flight", http.StatusConflict, "a request with this idempotency key is still in progress")
+
+	// 412 StatusPreconditionFailed
+	ErrVersionMismatch = NewError("version_mismatch", http.StatusPreconditionFailed, "customer was modified by another request")
+
+	// 413 StatusRequestEntityTooLarge
+	ErrBodyTooLarge = NewError("body_too_large", http.StatusRequestEntityTooLarge, "request body is too large")
+
+	// 422 StatusUnprocessableEntity
+	ErrIdempotencyKeyReused = NewError("idempotency_key_reused", http.StatusUnprocessableEntity, "idempotency key was already used with a different request")
+
+	// 503 StatusServiceUnavailable
+	ErrDatabaseBusy = NewError("database_busy", http.StatusServiceUnavailable, "the database is busy, retry the request")
+
+	// 504 StatusGatewayTimeout
+	ErrTimeout = NewError("timeout", http.StatusGatewayTimeout, "the request took too long")
 )
 
-func GetStatusCode(err error) int {
-	if err == nil {
-		return http.StatusOK
+// Lookup returns the declared Error err is or wraps. A deadline reported by any layer is
+// ErrTimeout, any other error is ErrInternalServerError.
+func Lookup(err error) *Error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
 	}
 
-	logrus.Error(err)
-	switch err {
-	case ErrInternalServerError:
-		return http.StatusInternalServerError
-
-	// 400 StatusBadRequest
-	case ErrUploadLimit:
-		return http.StatusBadRequest
-	case ErrInvalidUserID:
-		return http.StatusBadRequest
-	case ErrIsNotWalkIn:
-		return http.StatusBadRequest
-	case ErrIsNotPass:
-		return http.StatusBadRequest
-	case ErrInvalidVideoID:
-		return http.StatusBadRequest
-	case ErrVdoAndUserIDNotMatch:
-		return http.StatusBadRequest
-	case ErrVoteLimit:
-		return http.StatusBadRequest
-	case ErrInValidVdoUrl:
-		return http.StatusBadRequest
-	case ErrInvalidRegistType:
-		return http.StatusBadRequest
-
-	// 401 StatusUnauthorized
-	case ErrStatusInvalidCredentials:
-		return http.StatusUnauthorized
-
-	// 403 StatusForbidden
-	case ErrPermissionDenied:
-		return http.StatusForbidden
-	case ErrInvalidRecaptcha:
-		return http.StatusForbidden
-
-	// 404 StatusNotFound
-	case ErrNotFound:
-		return http.StatusNotFound
-	case ErrProvinceNotFound:
-		return http.StatusNotFound
-	case ErrUsernameNotFound:
-		return http.StatusNotFound
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr
+	}
+	return ErrInternalServerError
+}
 
-	// 409 StatusConflict
-	case ErrEmailExist:
-		return http.StatusConflict
-	case ErrPhoneExist:
-		return http.StatusConflict
-	case ErrIdNumberExist:
-		return http.StatusConflict
-	case ErrScoreExist:
-		return http.StatusConflict
-	case ErrCriState:
-		return http.StatusConflict
-	case ErrVdoUrlExist:
-		return http.StatusConflict
-
-	default:
-		return http.StatusInternalServerError
+func GetStatusCode(err error) int {
+	if err == nil {
+		return http.StatusOK
 	}
+	return Lookup(err).Status
 }
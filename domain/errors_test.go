@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStatusCode(t *testing.T) {
+	assert.Equal(t, http.StatusOK, GetStatusCode(nil))
+	assert.Equal(t, http.StatusNotFound, GetStatusCode(ErrNotFound))
+	assert.Equal(t, http.StatusNotFound, GetStatusCode(fmt.Errorf("find customer 42: %w", ErrNotFound)))
+	assert.Equal(t, http.StatusConflict, GetStatusCode(ErrConflict))
+	assert.Equal(t, http.StatusBadRequest, GetStatusCode(ErrBadParamInput))
+	assert.Equal(t, http.StatusConflict, GetStatusCode(ErrUsernameExist))
+	assert.Equal(t, http.StatusConflict, GetStatusCode(ErrDupPhoneExist))
+	assert.Equal(t, http.StatusNotFound, GetStatusCode(ErrScoutNotFound))
+	assert.Equal(t, http.StatusGatewayTimeout, GetStatusCode(fmt.Errorf("list customers: %w", context.DeadlineExceeded)))
+	assert.Equal(t, http.StatusInternalServerError, GetStatusCode(errors.New("disk I/O error")))
+}
+
+func TestLookup(t *testing.T) {
+	wrapped := fmt.Errorf("update customer: %w", ErrVersionMismatch)
+	assert.Same(t, ErrVersionMismatch, Lookup(wrapped))
+	assert.ErrorIs(t, wrapped, ErrVersionMismatch)
+	assert.Same(t, ErrInternalServerError, Lookup(errors.New("boom")))
+}
+
+func TestErrors(t *testing.T) {
+	errs := Errors()
+	assert.Len(t, errs, len(registry))
+
+	codes := map[string]bool{}
+	for i, err := range errs {
+		assert.NotEmpty(t, err.Code)
+		assert.NotEmpty(t, err.Message)
+		assert.False(t, codes[err.Code], err.Code)
+		codes[err.Code] = true
+
+		if i > 0 {
+			assert.LessOrEqual(t, errs[i-1].Status, err.Status)
+		}
+	}
+
+	assert.Panics(t, func() { NewError(ErrNotFound.Code, http.StatusNotFound, "again") })
+}
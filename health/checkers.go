@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"itmx_test/migration"
+
+	"gorm.io/gorm"
+)
+
+// errDiskUnsupported is returned by freeBytes where free disk space cannot be read
+var errDiskUnsupported = errors.New("free disk space is not available on this platform")
+
+// DatabaseChecker pings the database and runs a trivial query through the connection pool
+func DatabaseChecker(db *gorm.DB) Checker {
+	return CheckerFunc("database", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		if err := sqlDB.PingContext(ctx); err != nil {
+			return err
+		}
+
+		var one int
+		return db.WithContext(ctx).Raw("SELECT 1").Scan(&one).Error
+	})
+}
+
+// MigrationChecker fails while migrations are pending or an applied one was edited, the
+// schema would not match what the service expects
+func MigrationChecker(db *gorm.DB) Checker {
+	return CheckerFunc("migrations", func(ctx context.Context) error {
+		migrator, err := migration.New(db)
+		if err != nil {
+			return err
+		}
+
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations, starting with %04d_%s",
+				len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	})
+}
+
+// DiskSpaceChecker fails when the file system holding path has less than minFree bytes
+// available. It always passes where free space cannot be read.
+func DiskSpaceChecker(path string, minFree uint64) Checker {
+	return CheckerFunc("disk", func(ctx context.Context) error {
+		free, err := freeBytes(filepath.Dir(path))
+		if errors.Is(err, errDiskUnsupported) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("%d bytes free next to %s, below the minimum of %d", free, path, minFree)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,38 @@
+package health
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	health *Health
+}
+
+// NewHandler registers the probes. Register it before the CORS and logging middleware:
+// orchestrator probes send no Origin header and would flood the access log.
+func NewHandler(f *fiber.App, health *Health) {
+	handler := &Handler{health}
+
+	f.Get("/health/live", handler.Live)
+	f.Get("/health/ready", handler.Ready)
+}
+
+// Live reports the process is running and serving requests, it checks no dependency so a
+// database outage does not get the process restarted
+func (hh *Handler) Live(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": StatusUp,
+	})
+}
+
+// Ready reports whether every dependency is usable, with 503 when one is not so traffic is
+// routed elsewhere
+func (hh *Handler) Ready(c *fiber.Ctx) error {
+	report := hh.health.Check(c.UserContext())
+
+	status := fiber.StatusOK
+	if report.Status != StatusUp {
+		status = fiber.StatusServiceUnavailable
+	}
+	return c.Status(status).JSON(report)
+}
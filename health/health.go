@@ -0,0 +1,146 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Checker reports whether one dependency of the service is usable. Check returns nil when it
+// is and should give up when ctx is done.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkerFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (cf checkerFunc) Name() string                    { return cf.name }
+func (cf checkerFunc) Check(ctx context.Context) error { return cf.check(ctx) }
+
+// CheckerFunc turns a function into a Checker
+func CheckerFunc(name string, check func(ctx context.Context) error) Checker {
+	return checkerFunc{name, check}
+}
+
+// Result is the outcome of one checker
+type Result struct {
+	Name      string `json:"name"`
+	Status    Status `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the outcome of every checker. Status is down when any check is.
+type Report struct {
+	Status    Status    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+	Checks    []Result  `json:"checks"`
+}
+
+type Config struct {
+	// CacheTTL is how long a report is reused, so frequent probes do not hammer the database
+	CacheTTL time.Duration
+	// Timeout bounds every check, a check still running is reported down
+	Timeout time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.Timeout <= 0 {
+		c.Timeout = 2 * time.Second
+	}
+	return c
+}
+
+// Health runs the checkers and caches their report
+type Health struct {
+	checkers []Checker
+	config   Config
+
+	mu     sync.Mutex
+	report *Report
+}
+
+var now = time.Now
+
+func New(config Config, checkers ...Checker) *Health {
+	return &Health{
+		checkers: checkers,
+		config:   config.withDefaults(),
+	}
+}
+
+// Check returns the cached report while it is younger than CacheTTL and runs every checker
+// concurrently otherwise. Concurrent callers wait for the same run instead of starting their own.
+func (h *Health) Check(ctx context.Context) *Report {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.report != nil && now().Sub(h.report.CheckedAt) < h.config.CacheTTL {
+		return h.report
+	}
+
+	report := &Report{
+		Status:    StatusUp,
+		CheckedAt: now(),
+		Checks:    make([]Result, len(h.checkers)),
+	}
+
+	var wg sync.WaitGroup
+	for i, checker := range h.checkers {
+		wg.Add(1)
+		go func(i int, checker Checker) {
+			defer wg.Done()
+			report.Checks[i] = h.run(ctx, checker)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+
+	h.report = report
+	return report
+}
+
+func (h *Health) run(ctx context.Context, checker Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	start := now()
+	done := make(chan error, 1)
+	go func() {
+		done <- checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{
+		Name:      checker.Name(),
+		Status:    StatusUp,
+		LatencyMs: now().Sub(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
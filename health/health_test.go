@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"itmx_test/migration"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func openDB(t *testing.T) (*gorm.DB, string) {
+	path := filepath.Join(t.TempDir(), "itmx.sqlite")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	require.NoError(t, err)
+
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	return db, path
+}
+
+// setClock freezes the package clock at start and returns a function to move it forward
+func setClock(t *testing.T, start time.Time) func(d time.Duration) {
+	current := start
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+	return func(d time.Duration) { current = current.Add(d) }
+}
+
+func TestHealth(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("down when any check is", func(t *testing.T) {
+		setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		health := New(Config{},
+			CheckerFunc("ok", func(ctx context.Context) error { return nil }),
+			CheckerFunc("broken", func(ctx context.Context) error { return errors.New("boom") }),
+		)
+
+		report := health.Check(ctx)
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, Result{Name: "ok", Status: StatusUp}, report.Checks[0])
+		assert.Equal(t, Result{Name: "broken", Status: StatusDown, Error: "boom"}, report.Checks[1])
+	})
+
+	t.Run("reports are cached", func(t *testing.T) {
+		advance := setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		var calls int32
+		health := New(Config{CacheTTL: 5 * time.Second}, CheckerFunc("counted", func(ctx context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		}))
+
+		health.Check(ctx)
+		advance(4 * time.Second)
+		health.Check(ctx)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+		advance(time.Second)
+		health.Check(ctx)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("slow check times out", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		health := New(Config{Timeout: 10 * time.Millisecond}, CheckerFunc("stuck", func(ctx context.Context) error {
+			<-release
+			return nil
+		}))
+
+		report := health.Check(ctx)
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+	})
+}
+
+func TestCheckers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("database", func(t *testing.T) {
+		db, _ := openDB(t)
+		assert.NoError(t, DatabaseChecker(db).Check(ctx))
+
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+		assert.Error(t, DatabaseChecker(db).Check(ctx))
+	})
+
+	t.Run("migrations", func(t *testing.T) {
+		db, _ := openDB(t)
+		assert.ErrorContains(t, MigrationChecker(db).Check(ctx), "pending migrations, starting with 0001_create_customers")
+
+		migrator, err := migration.New(db)
+		require.NoError(t, err)
+		_, err = migrator.Up(ctx)
+		require.NoError(t, err)
+		assert.NoError(t, MigrationChecker(db).Check(ctx))
+	})
+
+	t.Run("disk space", func(t *testing.T) {
+		_, path := openDB(t)
+		assert.NoError(t, DiskSpaceChecker(path, 1).Check(ctx))
+		assert.ErrorContains(t, DiskSpaceChecker(path, 1<<62).Check(ctx), "below the minimum")
+	})
+}
+
+func TestHandler(t *testing.T) {
+	healthy := true
+	f := fiber.New()
+	NewHandler(f, New(Config{}, CheckerFunc("toggle", func(ctx context.Context) error {
+		if !healthy {
+			return errors.New("unavailable")
+		}
+		return nil
+	})))
+
+	resp, err := f.Test(httptest.NewRequest("GET", "/health/live", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = f.Test(httptest.NewRequest("GET", "/health/ready", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	healthy = false
+	resp, err = f.Test(httptest.NewRequest("GET", "/health/ready", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	report := &Report{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(report))
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, "unavailable", report.Checks[0].Error)
+
+	// liveness does not depend on the checks
+	resp, err = f.Test(httptest.NewRequest("GET", "/health/live", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// GormLogger writes the gorm logs through the logger of the query context, so they carry the
+// request ID. Failed queries are logged at error level and queries slower than SlowThreshold
+// at warn level, the others only at trace level.
+type GormLogger struct {
+	SlowThreshold time.Duration
+	level         logger.LogLevel
+}
+
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{SlowThreshold: slowThreshold, level: logger.Warn}
+}
+
+func (gl *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *gl
+	copied.level = level
+	return &copied
+}
+
+func (gl *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if gl.level >= logger.Info {
+		FromContext(ctx).Infof(msg, args...)
+	}
+}
+
+func (gl *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if gl.level >= logger.Warn {
+		FromContext(ctx).Warnf(msg, args...)
+	}
+}
+
+func (gl *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if gl.level >= logger.Error {
+		FromContext(ctx).Errorf(msg, args...)
+	}
+}
+
+func (gl *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if gl.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	slow := gl.SlowThreshold > 0 && elapsed > gl.SlowThreshold
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+
+	log := FromContext(ctx)
+	if !failed && !slow && !log.Logger.IsLevelEnabled(logrus.TraceLevel) {
+		return
+	}
+
+	sql, rows := fc()
+	log = log.WithFields(logrus.Fields{
+		"sql":        sql,
+		"rows":       rows,
+		"elapsed_ms": float64(elapsed.Microseconds()) / 1000,
+	})
+
+	switch {
+	case failed && gl.level >= logger.Error:
+		log.WithError(err).Error("query failed")
+	case slow && gl.level >= logger.Warn:
+		log.WithField("threshold_ms", gl.SlowThreshold.Milliseconds()).Warn("slow query")
+	default:
+		log.Trace("query")
+	}
+}
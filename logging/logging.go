@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type Config struct {
+	Level  string
+	Format string
+}
+
+// Setup configures the standard logger every other logger derives from
+func Setup(config Config) error {
+	level, err := logrus.ParseLevel(config.Level)
+	if err != nil {
+		return err
+	}
+	logrus.SetLevel(level)
+	logrus.SetOutput(os.Stderr)
+
+	switch config.Format {
+	case FormatJSON, "":
+		logrus.SetFormatter(&logrus.JSONFormatter{TimestampFormat: "2006-01-02T15:04:05.000Z07:00"})
+	case FormatText:
+		logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("unsupported log format %q", config.Format)
+	}
+	return nil
+}
+
+type loggerKey struct{}
+
+// WithFields returns a copy of ctx whose logger carries fields on top of those it had
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return context.WithValue(ctx, loggerKey{}, entry(ctx).WithFields(fields))
+}
+
+// FromContext returns the logger of ctx, with the fields added by WithFields and the trace
+// ID of the current span, or the standard logger
+func FromContext(ctx context.Context) *logrus.Entry {
+	logger := entry(ctx)
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		logger = logger.WithField("trace_id", spanContext.TraceID().String())
+	}
+	return logger.WithContext(ctx)
+}
+
+func entry(ctx context.Context) *logrus.Entry {
+	if logger, ok := ctx.Value(loggerKey{}).(*logrus.Entry); ok {
+		return logger
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// RequestError logs the error a request was answered with: server errors at error level, the
+// errors of the client at info level
+func RequestError(ctx context.Context, status int, err error) {
+	logger := FromContext(ctx).WithError(err).WithField("status", status)
+	if status >= 500 {
+		logger.Error("request failed")
+		return
+	}
+	logger.Info("request rejected")
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestFromContext(t *testing.T) {
+	hook := test.NewGlobal()
+
+	ctx := WithFields(context.Background(), logrus.Fields{"request_id": "req-1"})
+	ctx = WithFields(ctx, logrus.Fields{"customer_id": "42"})
+	FromContext(ctx).Info("hello")
+
+	entry := hook.LastEntry()
+	assert.Equal(t, "req-1", entry.Data["request_id"])
+	assert.Equal(t, "42", entry.Data["customer_id"])
+	assert.NotContains(t, entry.Data, "trace_id")
+
+	traceID, _ := trace.TraceIDFromHex("0123456789abcdef0123456789abcdef")
+	spanID, _ := trace.SpanIDFromHex("0123456789abcdef")
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	FromContext(ctx).Info("traced")
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", hook.LastEntry().Data["trace_id"])
+
+	// the standard logger when ctx has none
+	FromContext(context.Background()).Info("bare")
+	assert.Empty(t, hook.LastEntry().Data)
+}
+
+func TestRequestError(t *testing.T) {
+	hook := test.NewGlobal()
+	ctx := context.Background()
+
+	RequestError(ctx, 500, errors.New("boom"))
+	assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+	assert.Equal(t, 500, hook.LastEntry().Data["status"])
+
+	RequestError(ctx, 404, errors.New("not found"))
+	assert.Equal(t, logrus.InfoLevel, hook.LastEntry().Level)
+	assert.Equal(t, "request rejected", hook.LastEntry().Message)
+}
+
+func TestGormLogger(t *testing.T) {
+	hook := test.NewGlobal()
+	ctx := WithFields(context.Background(), logrus.Fields{"request_id": "req-1"})
+	query := func() (string, int64) { return "SELECT 1", 1 }
+
+	gormLogger := NewGormLogger(100 * time.Millisecond)
+
+	gormLogger.Trace(ctx, time.Now(), query, nil)
+	assert.Empty(t, hook.AllEntries())
+
+	gormLogger.Trace(ctx, time.Now().Add(-time.Second), query, nil)
+	entry := hook.LastEntry()
+	assert.Equal(t, logrus.WarnLevel, entry.Level)
+	assert.Equal(t, "slow query", entry.Message)
+	assert.Equal(t, "SELECT 1", entry.Data["sql"])
+	assert.Equal(t, int64(100), entry.Data["threshold_ms"])
+	assert.Equal(t, "req-1", entry.Data["request_id"])
+
+	gormLogger.Trace(ctx, time.Now(), query, errors.New("no such table"))
+	assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+	assert.Equal(t, "query failed", hook.LastEntry().Message)
+
+	hook.Reset()
+	gormLogger.Trace(ctx, time.Now(), query, gorm.ErrRecordNotFound)
+	assert.Empty(t, hook.AllEntries())
+
+	// a zero threshold turns slow query logging off
+	NewGormLogger(0).Trace(ctx, time.Now().Add(-time.Hour), query, nil)
+	assert.Empty(t, hook.AllEntries())
+
+	gormLogger.LogMode(logger.Silent).Trace(ctx, time.Now(), query, errors.New("no such table"))
+	assert.Empty(t, hook.AllEntries())
+}
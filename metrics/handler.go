@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels the requests no route handled, so unknown paths cannot grow the
+// number of series
+const unmatchedRoute = "unmatched"
+
+// NewHandler registers GET /metrics in the Prometheus text format
+func NewHandler(f *fiber.App) {
+	f.Get("/metrics", adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})))
+}
+
+// Middleware counts and times every request by its route template, /customers/:id rather
+// than the requested path
+func Middleware() fiber.Handler {
+	var once sync.Once
+	routes := map[string]bool{}
+
+	return func(c *fiber.Ctx) error {
+		// every route is registered by the time requests are served
+		once.Do(func() {
+			for _, r := range c.App().GetRoutes(true) {
+				routes[r.Method+" "+r.Path] = true
+			}
+		})
+
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// the error handler has not written the response yet
+			status = fiber.StatusInternalServerError
+			if fiberErr, ok := err.(*fiber.Error); ok {
+				status = fiberErr.Code
+			}
+		}
+
+		// c.Route is the last handler run, a middleware when the request was rejected
+		// before reaching its route or matched none
+		route := unmatchedRoute
+		if r := c.Route(); routes[r.Method+" "+r.Path] {
+			route = r.Path
+		}
+
+		// fiber reuses the request buffers, the label outlives the request
+		labels := []string{utils.CopyString(c.Method()), route, strconv.Itoa(status)}
+		httpRequests.WithLabelValues(labels...).Inc()
+		httpDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
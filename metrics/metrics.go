@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "itmx"
+
+// Registry holds every metric exposed on /metrics. A registry of our own keeps the metrics of
+// imported libraries out unless they are added here.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency by gorm operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	dbQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Failed database queries by gorm operation and table, record not found excluded.",
+	}, []string{"operation", "table"})
+
+	customerChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "customer_changes_total",
+		Help:      "Committed customer changes by operation: create, update, delete, restore or purge.",
+	}, []string{"operation"})
+)
+
+// dbStats is the pool collector of the database registered last
+var dbStats prometheus.Collector
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		dbQueryDuration,
+		dbQueryErrors,
+		customerChanges,
+	)
+}
+
+// CountCustomerChanges adds n committed changes of the audit operation
+func CountCustomerChanges(operation string, n int) {
+	if n > 0 {
+		customerChanges.WithLabelValues(operation).Add(float64(n))
+	}
+}
+
+// RegisterDBStats exposes the connection pool statistics of db, replacing those of a database
+// registered before
+func RegisterDBStats(db *sql.DB, name string) error {
+	if dbStats != nil {
+		Registry.Unregister(dbStats)
+	}
+	dbStats = collectors.NewDBStatsCollector(db, name)
+	return Registry.Register(dbStats)
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMiddleware(t *testing.T) {
+	f := fiber.New()
+	f.Use(Middleware())
+	f.Use(func(c *fiber.Ctx) error {
+		if c.Get("X-Reject") != "" {
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+		return c.Next()
+	})
+	f.Get("/things/:id", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	f.Post("/things", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	_, err := f.Test(httptest.NewRequest("POST", "/things", nil))
+	require.NoError(t, err)
+
+	for _, path := range []string{"/things/1", "/things/2", "/nowhere"} {
+		_, err := f.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+	}
+	req := httptest.NewRequest("GET", "/things/3", nil)
+	req.Header.Set("X-Reject", "1")
+	_, err = f.Test(req)
+	require.NoError(t, err)
+
+	// requests are grouped by route template, unknown paths share one series
+	assert.Equal(t, float64(2), testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/things/:id", "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpRequests.WithLabelValues("GET", unmatchedRoute, "404")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpRequests.WithLabelValues("GET", unmatchedRoute, "403")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpRequests.WithLabelValues("POST", "/things", "201")))
+	assert.Equal(t, 4, testutil.CollectAndCount(httpDuration))
+}
+
+func TestGormPlugin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "itmx.sqlite")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GormPlugin{}))
+
+	type thing struct {
+		ID   uint
+		Name string
+	}
+	require.NoError(t, db.AutoMigrate(&thing{}))
+	require.NoError(t, db.Create(&thing{Name: "a"}).Error)
+	assert.Error(t, db.First(&thing{}, 42).Error)
+	assert.Error(t, db.Exec("SELECT * FROM missing").Error)
+
+	created := &dto.Metric{}
+	require.NoError(t, dbQueryDuration.WithLabelValues("create", "things").(prometheus.Histogram).Write(created))
+	assert.Equal(t, uint64(1), created.GetHistogram().GetSampleCount())
+	assert.Equal(t, float64(0), testutil.ToFloat64(dbQueryErrors.WithLabelValues("query", "things")), "record not found is no error")
+	assert.Equal(t, float64(1), testutil.ToFloat64(dbQueryErrors.WithLabelValues("raw", "")))
+}
+
+func TestHandler(t *testing.T) {
+	CountCustomerChanges("create", 3)
+
+	sqlDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	pool, _ := sqlDB.DB()
+	require.NoError(t, RegisterDBStats(pool, "sqlite"))
+	// registering another database replaces the first one
+	require.NoError(t, RegisterDBStats(pool, "sqlite"))
+
+	f := fiber.New()
+	NewHandler(f)
+
+	resp, err := f.Test(httptest.NewRequest("GET", "/metrics", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), `itmx_customer_changes_total{operation="create"} 3`)
+	assert.Contains(t, string(body), `go_sql_open_connections{db_name="sqlite"}`)
+	assert.Contains(t, string(body), "go_goroutines")
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"time"
+
+	"itmx_test/logging"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// AccessLogMiddleware logs one line per request with its route template, status and latency,
+// through the request logger so it carries the request ID. It hands the error returned by the
+// handlers after it to the error handler.
+func AccessLogMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		// the error is rendered here rather than once the chain returns, so the status logged
+		// is the one sent and the middleware before this one see it too
+		if err := c.Next(); err != nil {
+			renderError(c, err)
+		}
+		status := c.Response().StatusCode()
+
+		logging.FromContext(c.UserContext()).WithFields(logrus.Fields{
+			"method":     c.Method(),
+			"path":       c.Path(),
+			"route":      c.Route().Path,
+			"status":     status,
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"ip":         c.IP(),
+		}).Info("request")
+
+		return nil
+	}
+}
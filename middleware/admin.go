@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"itmx_test/domain"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const adminLocalKey = "is_admin"
+
+// AdminMiddleware marks the request as admin when it carries the configured admin token,
+// either as "Authorization: Bearer <token>" or in the X-Admin-Token header. An empty token
+// disables admin access entirely.
+func AdminMiddleware(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token == "" {
+			return c.Next()
+		}
+
+		provided := c.Get("X-Admin-Token")
+		if auth := c.Get(fiber.HeaderAuthorization); strings.HasPrefix(auth, "Bearer ") {
+			provided = strings.TrimPrefix(auth, "Bearer ")
+		}
+
+		if provided != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+			c.Locals(adminLocalKey, true)
+		}
+
+		return c.Next()
+	}
+}
+
+func IsAdmin(c *fiber.Ctx) bool {
+	isAdmin, _ := c.Locals(adminLocalKey).(bool)
+	return isAdmin
+}
+
+// AdminPrincipal is the principal of a request carrying the admin token
+const AdminPrincipal = "admin"
+
+// Principal is the authenticated identity of the request, AdminPrincipal or empty for an
+// anonymous request. Identities claimed in client supplied headers are never taken.
+func Principal(c *fiber.Ctx) string {
+	if IsAdmin(c) {
+		return AdminPrincipal
+	}
+	return ""
+}
+
+// AdminOnly rejects requests that were not marked as admin by AdminMiddleware
+func AdminOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !IsAdmin(c) {
+			return domain.ErrPermissionDenied
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"io"
+	"strings"
+
+	"itmx_test/domain"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimitMiddleware answers 413 to requests whose body is larger than limit bytes. The server
+// streams request bodies for the customer import, so a body over fiber's BodyLimit reaches the
+// handlers unread and c.Body() or BodyParser would read it into memory whatever its size. The
+// body is read here instead, at most limit bytes of it. The routes in streamed, written as
+// "METHOD /path", read their body as a stream themselves and are left alone. A limit of 0
+// disables the check.
+func BodyLimitMiddleware(limit int, streamed ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if limit <= 0 || isStreamed(streamed, c.Method(), c.Path()) {
+			return c.Next()
+		}
+
+		// a body within fiber's BodyLimit is already buffered
+		stream := c.Context().RequestBodyStream()
+		if stream == nil {
+			if len(c.Body()) > limit {
+				return bodyTooLarge(c)
+			}
+			return c.Next()
+		}
+
+		if c.Request().Header.ContentLength() > limit {
+			return bodyTooLarge(c)
+		}
+
+		// a chunked body has no length, it is read until it passes the limit
+		body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+		if err != nil {
+			return err
+		}
+		if len(body) > limit {
+			return bodyTooLarge(c)
+		}
+
+		c.Request().SetBody(body)
+		return c.Next()
+	}
+}
+
+// bodyTooLarge closes the connection rather than read the rest of the body
+func bodyTooLarge(c *fiber.Ctx) error {
+	c.Context().SetConnectionClose()
+	return domain.ErrBodyTooLarge
+}
+
+func isStreamed(streamed []string, method, path string) bool {
+	for _, route := range streamed {
+		routeMethod, routePath, _ := strings.Cut(route, " ")
+		if strings.EqualFold(routeMethod, method) && strings.TrimRight(routePath, "/") == strings.TrimRight(path, "/") {
+			return true
+		}
+	}
+	return false
+}
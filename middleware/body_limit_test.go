@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"itmx_test/problem"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	const limit = 64
+
+	app := fiber.New(fiber.Config{
+		StreamRequestBody: true,
+		BodyLimit:         limit,
+		ErrorHandler:      ErrorHandler,
+	})
+	app.Use(BodyLimitMiddleware(limit, "POST /customers/import"))
+	app.Post("/customers", func(c *fiber.Ctx) error {
+		var input map[string]interface{}
+		if err := c.BodyParser(&input); err != nil {
+			return problem.MalformedBody(err)
+		}
+		return c.JSON(input)
+	})
+	app.Post("/customers/import", func(c *fiber.Ctx) error {
+		n, err := io.Copy(io.Discard, c.Context().RequestBodyStream())
+		if err != nil {
+			return err
+		}
+		return c.SendString(strconv.FormatInt(n, 10))
+	})
+
+	send := func(path, body string, chunked bool) (int, string) {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if chunked {
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(respBody)
+	}
+
+	oversized := `{"name":"` + strings.Repeat("a", 2*limit) + `"}`
+
+	t.Run("body within the limit", func(t *testing.T) {
+		code, body := send("/customers", `{"name":"John"}`, false)
+		assert.Equal(t, fiber.StatusOK, code)
+		assert.JSONEq(t, `{"name":"John"}`, body)
+	})
+
+	t.Run("oversized JSON body", func(t *testing.T) {
+		code, body := send("/customers", oversized, false)
+		assert.Equal(t, fiber.StatusRequestEntityTooLarge, code)
+		assert.Contains(t, body, `"code":"body_too_large"`)
+	})
+
+	t.Run("oversized chunked JSON body", func(t *testing.T) {
+		code, body := send("/customers", oversized, true)
+		assert.Equal(t, fiber.StatusRequestEntityTooLarge, code)
+		assert.Contains(t, body, `"code":"body_too_large"`)
+	})
+
+	t.Run("chunked body within the limit", func(t *testing.T) {
+		code, body := send("/customers", `{"name":"John"}`, true)
+		assert.Equal(t, fiber.StatusOK, code)
+		assert.JSONEq(t, `{"name":"John"}`, body)
+	})
+
+	t.Run("streamed route is not limited", func(t *testing.T) {
+		code, body := send("/customers/import", oversized, false)
+		assert.Equal(t, fiber.StatusOK, code)
+		assert.Equal(t, strconv.Itoa(len(oversized)), body)
+	})
+}
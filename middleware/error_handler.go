@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"itmx_test/logging"
+	"itmx_test/problem"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrorHandler is the fiber.Config.ErrorHandler. It logs the error a handler or middleware
+// returned and renders it as an application/problem+json document whose instance is the
+// request ID.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	rendered := *problem.FromError(err)
+	rendered.Instance = RequestID(c)
+
+	ctx := logging.WithFields(c.UserContext(), logrus.Fields{
+		"route": c.Route().Path,
+		"code":  rendered.Code,
+	})
+	logging.RequestError(ctx, rendered.Status, err)
+
+	body, err := rendered.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, problem.ContentType)
+	if rendered.Status == fiber.StatusServiceUnavailable {
+		// the condition is transient, such as a locked database
+		c.Set(fiber.HeaderRetryAfter, "1")
+	}
+	return c.Status(rendered.Status).Send(body)
+}
+
+// renderError writes the response of err with the error handler of the app, for middleware
+// that need the response of a failed request before the chain returns
+func renderError(c *fiber.Ctx, err error) {
+	if err := c.App().Config().ErrorHandler(c, err); err != nil {
+		_ = c.SendStatus(fiber.StatusInternalServerError)
+	}
+}
+
+// RecoverMiddleware turns a panic in the handlers after it into a 500 problem and logs the
+// stack trace
+func RecoverMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logging.FromContext(c.UserContext()).WithField("stack", string(debug.Stack())).Errorf("panic: %v", r)
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"itmx_test/domain"
+	"itmx_test/problem"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(RequestIDMiddleware())
+	app.Use(AccessLogMiddleware())
+	app.Use(RecoverMiddleware())
+	app.Get("/missing", func(c *fiber.Ctx) error {
+		return domain.ErrNotFound
+	})
+	app.Get("/panic", func(c *fiber.Ctx) error {
+		panic("boom")
+	})
+
+	request := func(path string) (int, string, problem.Problem) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(fiber.HeaderXRequestID, "req-1")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		var body problem.Problem
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, resp.Header.Get(fiber.HeaderContentType), body
+	}
+
+	t.Run("domain error", func(t *testing.T) {
+		status, contentType, body := request("/missing")
+		assert.Equal(t, fiber.StatusNotFound, status)
+		assert.Equal(t, problem.ContentType, contentType)
+		assert.Equal(t, fiber.StatusNotFound, body.Status)
+		assert.Equal(t, "not_found", body.Code)
+		assert.Equal(t, domain.ErrNotFound.Error(), body.Detail)
+		assert.Equal(t, "req-1", body.Instance)
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		status, contentType, body := request("/nowhere")
+		assert.Equal(t, fiber.StatusNotFound, status)
+		assert.Equal(t, problem.ContentType, contentType)
+		assert.Equal(t, "not_found", body.Code)
+	})
+
+	t.Run("recovered panic", func(t *testing.T) {
+		status, _, body := request("/panic")
+		assert.Equal(t, fiber.StatusInternalServerError, status)
+		assert.Equal(t, domain.ErrInternalServerError.Code, body.Code)
+		assert.Equal(t, domain.ErrInternalServerError.Message, body.Detail)
+		assert.Equal(t, "req-1", body.Instance)
+	})
+}
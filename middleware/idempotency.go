@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"itmx_test/domain"
+	"itmx_test/logging"
+	"itmx_test/service/entity"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	HeaderIdempotencyKey       = "Idempotency-Key"
+	HeaderIdempotentReplayed   = "Idempotent-Replayed"
+	maxIdempotencyKeyLength    = 255
+	defaultIdempotencyKeyTTL   = 24 * time.Hour
+	defaultIdempotencyKeyLease = time.Minute
+	// idempotencyStoreTimeout bounds storing or releasing the response once the request is done
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+// IdempotencyStore keeps idempotency records, see service/idempotency/repository
+type IdempotencyStore interface {
+	FindByKey(ctx context.Context, key string, now time.Time) (*entity.IdempotencyRecord, error)
+	Reserve(ctx context.Context, record *entity.IdempotencyRecord) error
+	Complete(ctx context.Context, record *entity.IdempotencyRecord) error
+	Release(ctx context.Context, record *entity.IdempotencyRecord) error
+}
+
+// IdempotencyMiddleware makes POST requests that carry an Idempotency-Key header safe to
+// retry. The first request with a key runs normally and its response is stored for ttl.
+// A retry with the same key and the same method, path and body gets the stored response
+// back, a retry with a different request gets 422 and a retry while the first one is still
+// running gets 409. Responses with a 5xx status are not stored so the client can try again.
+// The key is reserved for lease while the first request runs, so a process that dies before
+// storing the response does not hold the key for the whole ttl.
+func IdempotencyMiddleware(store IdempotencyStore, ttl, lease time.Duration) fiber.Handler {
+	if ttl <= 0 {
+		ttl = defaultIdempotencyKeyTTL
+	}
+	if lease <= 0 {
+		lease = defaultIdempotencyKeyLease
+	}
+	if lease > ttl {
+		lease = ttl
+	}
+
+	return func(c *fiber.Ctx) error {
+		key := c.Get(HeaderIdempotencyKey)
+		if key == "" || c.Method() != fiber.MethodPost {
+			return c.Next()
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			return domain.ErrInvalidIdempotencyKey
+		}
+
+		ctx := c.UserContext()
+		fingerprint := requestFingerprint(c)
+		// Complete and Release find the reservation by its creation time, kept at the millisecond
+		// precision every database stores
+		now := time.Now().Truncate(time.Millisecond)
+
+		record, err := store.FindByKey(ctx, key, now)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+		if record != nil {
+			return replay(c, record, fingerprint)
+		}
+
+		record = &entity.IdempotencyRecord{
+			Key:         key,
+			Fingerprint: fingerprint,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(lease),
+		}
+		if err := store.Reserve(ctx, record); err != nil {
+			if errors.Is(err, domain.ErrConflict) {
+				return domain.ErrIdempotencyInFlight
+			}
+			return err
+		}
+
+		// an error is rendered now, a 4xx is stored like any other response
+		if err := c.Next(); err != nil {
+			renderError(c, err)
+		}
+
+		// the request context is done when the handler timed out or the client went away, the
+		// key is settled all the same
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyStoreTimeout)
+		defer cancel()
+
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			releaseKey(ctx, store, record)
+			return nil
+		}
+
+		record.StatusCode = status
+		record.ContentType = string(c.Response().Header.ContentType())
+		record.Body = append([]byte(nil), c.Response().Body()...)
+		record.ExpiresAt = time.Now().Add(ttl)
+		if err := store.Complete(ctx, record); err != nil {
+			if errors.Is(err, domain.ErrConflict) {
+				logging.FromContext(ctx).WithField("idempotency_key", key).Warn("idempotency key was reserved again before the response was stored")
+				return nil
+			}
+			logging.FromContext(ctx).WithError(err).WithField("idempotency_key", key).Error("store idempotent response")
+			releaseKey(ctx, store, record)
+		}
+
+		return nil
+	}
+}
+
+// releaseKey frees the key for a retry. A key that cannot be released stays reserved until its
+// lease runs out, one reserved again by a retry is left to it.
+func releaseKey(ctx context.Context, store IdempotencyStore, record *entity.IdempotencyRecord) {
+	err := store.Release(ctx, record)
+	if errors.Is(err, domain.ErrConflict) {
+		logging.FromContext(ctx).WithField("idempotency_key", record.Key).Warn("idempotency key was reserved again before it was released")
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("idempotency_key", record.Key).Error("release idempotency key")
+	}
+}
+
+func replay(c *fiber.Ctx, record *entity.IdempotencyRecord, fingerprint string) error {
+	if record.Fingerprint != fingerprint {
+		return domain.ErrIdempotencyKeyReused
+	}
+	if !record.Completed() {
+		return domain.ErrIdempotencyInFlight
+	}
+
+	c.Set(HeaderIdempotentReplayed, "true")
+	if record.ContentType != "" {
+		c.Set(fiber.HeaderContentType, record.ContentType)
+	}
+	return c.Status(record.StatusCode).Send(record.Body)
+}
+
+// requestFingerprint identifies a request by method, path and body
+func requestFingerprint(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method() + " " + c.OriginalURL() + "\n"))
+	hash.Write(c.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,280 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"itmx_test/domain"
+	"itmx_test/service/entity"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryIdempotencyStore is an in-memory IdempotencyStore
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*entity.IdempotencyRecord
+
+	// findErr is returned by FindByKey when set
+	findErr error
+}
+
+func (m *memoryIdempotencyStore) FindByKey(ctx context.Context, key string, now time.Time) (*entity.IdempotencyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.findErr != nil {
+		return nil, m.findErr
+	}
+	record, ok := m.records[key]
+	if !ok || !record.ExpiresAt.After(now) {
+		return nil, domain.ErrNotFound
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func (m *memoryIdempotencyStore) Reserve(ctx context.Context, record *entity.IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.records[record.Key]; ok && existing.ExpiresAt.After(record.CreatedAt) {
+		return domain.ErrConflict
+	}
+	copied := *record
+	m.records[record.Key] = &copied
+	return nil
+}
+
+func (m *memoryIdempotencyStore) Complete(ctx context.Context, record *entity.IdempotencyRecord) error {
+	// like a database query, nothing is written once the context is done
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.owns(record) {
+		return domain.ErrConflict
+	}
+	copied := *record
+	m.records[record.Key] = &copied
+	return nil
+}
+
+func (m *memoryIdempotencyStore) Release(ctx context.Context, record *entity.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.owns(record) {
+		return domain.ErrConflict
+	}
+	delete(m.records, record.Key)
+	return nil
+}
+
+// owns reports whether the stored reservation of the key is record's
+func (m *memoryIdempotencyStore) owns(record *entity.IdempotencyRecord) bool {
+	existing, ok := m.records[record.Key]
+	return ok && existing.CreatedAt.Equal(record.CreatedAt)
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	newApp := func(store IdempotencyStore, status *int, calls *int) *fiber.App {
+		app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		app.Post("/customers", IdempotencyMiddleware(store, time.Hour, time.Minute), func(c *fiber.Ctx) error {
+			*calls++
+			return c.Status(*status).JSON(fiber.Map{"call": *calls})
+		})
+		return app
+	}
+
+	send := func(app *fiber.App, key, body string) (int, string, string) {
+		req := httptest.NewRequest("POST", "/customers", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(respBody), resp.Header.Get(HeaderIdempotentReplayed)
+	}
+
+	t.Run("replays the stored response", func(t *testing.T) {
+		status, calls := fiber.StatusCreated, 0
+		app := newApp(&memoryIdempotencyStore{records: map[string]*entity.IdempotencyRecord{}}, &status, &calls)
+
+		code, body, replayed := send(app, "key-1", `{"name":"a","age":1}`)
+		assert.Equal(t, fiber.StatusCreated, code)
+		assert.Equal(t, `{"call":1}`, body)
+		assert.Empty(t, replayed)
+
+		code, body, replayed = send(app, "key-1", `{"name":"a","age":1}`)
+		assert.Equal(t, fiber.StatusCreated, code)
+		assert.Equal(t, `{"call":1}`, body)
+		assert.Equal(t, "true", replayed)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("different body with the same key", func(t *testing.T) {
+		status, calls := fiber.StatusCreated, 0
+		app := newApp(&memoryIdempotencyStore{records: map[string]*entity.IdempotencyRecord{}}, &status, &calls)
+
+		send(app, "key-1", `{"name":"a","age":1}`)
+		code, _, _ := send(app, "key-1", `{"name":"b","age":1}`)
+		assert.Equal(t, fiber.StatusUnprocessableEntity, code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("request still in progress", func(t *testing.T) {
+		var app *fiber.App
+		retryStatus := 0
+		app = fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		app.Post("/customers", IdempotencyMiddleware(&memoryIdempotencyStore{records: map[string]*entity.IdempotencyRecord{}}, time.Hour, time.Minute), func(c *fiber.Ctx) error {
+			// the client retries before the first request has finished
+			retryStatus, _, _ = send(app, "key-1", `{}`)
+			return c.SendStatus(fiber.StatusCreated)
+		})
+
+		code, _, _ := send(app, "key-1", `{}`)
+		assert.Equal(t, fiber.StatusCreated, code)
+		assert.Equal(t, fiber.StatusConflict, retryStatus)
+	})
+
+	t.Run("server errors are not stored", func(t *testing.T) {
+		status, calls := fiber.StatusInternalServerError, 0
+		app := newApp(&memoryIdempotencyStore{records: map[string]*entity.IdempotencyRecord{}}, &status, &calls)
+
+		code, _, _ := send(app, "key-1", `{}`)
+		assert.Equal(t, fiber.StatusInternalServerError, code)
+
+		status = fiber.StatusCreated
+		code, _, _ = send(app, "key-1", `{}`)
+		assert.Equal(t, fiber.StatusCreated, code)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("client errors returned by the handler are stored", func(t *testing.T) {
+		calls := 0
+		app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		app.Post("/customers", IdempotencyMiddleware(&memoryIdempotencyStore{records: map[string]*entity.IdempotencyRecord{}}, time.Hour, time.Minute), func(c *fiber.Ctx) error {
+			calls++
+			return domain.ErrVersionMismatch
+		})
+
+		code, first, _ := send(app, "key-1", `{}`)
+		assert.Equal(t, fiber.StatusPreconditionFailed, code)
+
+		code, body, replayed := send(app, "key-1", `{}`)
+		assert.Equal(t, fiber.StatusPreconditionFailed, code)
+		assert.Equal(t, first, body)
+		assert.Equal(t, "true", replayed)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("a timed out request can be retried", func(t *testing.T) {
+		calls := 0
+		app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		app.Use(TimeoutMiddleware(TimeoutConfig{Default: 20 * time.Millisecond}))
+		app.Post("/customers", IdempotencyMiddleware(&memoryIdempotencyStore{records: map[string]*entity.IdempotencyRecord{}}, time.Hour, time.Minute), func(c *fiber.Ctx) error {
+			calls++
+			if calls == 1 {
+				<-c.UserContext().Done()
+				return c.UserContext().Err()
+			}
+			return c.SendStatus(fiber.StatusCreated)
+		})
+
+		code, _, _ := send(app, "key-1", `{}`)
+		assert.Equal(t, fiber.StatusGatewayTimeout, code)
+
+		code, _, replayed := send(app, "key-1", `{}`)
+		assert.Equal(t, fiber.StatusCreated, code)
+		assert.Empty(t, replayed)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("the key is leased while the request runs", func(t *testing.T) {
+		store := &memoryIdempotencyStore{records: map[string]*entity.IdempotencyRecord{}}
+		var leasedUntil time.Time
+		app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		app.Post("/customers", IdempotencyMiddleware(store, time.Hour, time.Minute), func(c *fiber.Ctx) error {
+			leasedUntil = store.records["key-1"].ExpiresAt
+			return c.SendStatus(fiber.StatusCreated)
+		})
+
+		start := time.Now()
+		code, _, _ := send(app, "key-1", `{}`)
+		assert.Equal(t, fiber.StatusCreated, code)
+		assert.WithinDuration(t, start.Add(time.Minute), leasedUntil, time.Second)
+		assert.WithinDuration(t, start.Add(time.Hour), store.records["key-1"].ExpiresAt, time.Second)
+	})
+
+	t.Run("a reservation left by a crash expires after its lease", func(t *testing.T) {
+		status, calls := fiber.StatusCreated, 0
+		now := time.Now()
+		store := &memoryIdempotencyStore{records: map[string]*entity.IdempotencyRecord{
+			"key-1": {Key: "key-1", Fingerprint: "crashed", CreatedAt: now.Add(-2 * time.Minute), ExpiresAt: now.Add(-time.Minute)},
+		}}
+		app := newApp(store, &status, &calls)
+
+		code, _, _ := send(app, "key-1", `{}`)
+		assert.Equal(t, fiber.StatusCreated, code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("a request that outlived its lease leaves the retry's reservation alone", func(t *testing.T) {
+		for _, status := range []int{fiber.StatusCreated, fiber.StatusInternalServerError} {
+			store := &memoryIdempotencyStore{records: map[string]*entity.IdempotencyRecord{}}
+			retry := &entity.IdempotencyRecord{Key: "key-1", Fingerprint: "retry", CreatedAt: time.Now().Add(time.Minute), ExpiresAt: time.Now().Add(2 * time.Minute)}
+			app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+			app.Post("/customers", IdempotencyMiddleware(store, time.Hour, time.Minute), func(c *fiber.Ctx) error {
+				// the lease ran out and a retry reserved the key again
+				store.records["key-1"] = retry
+				return c.SendStatus(status)
+			})
+
+			code, _, _ := send(app, "key-1", `{}`)
+			assert.Equal(t, status, code)
+			assert.Same(t, retry, store.records["key-1"])
+			assert.False(t, retry.Completed())
+		}
+	})
+
+	t.Run("store failures do not run the request", func(t *testing.T) {
+		status, calls := fiber.StatusCreated, 0
+		store := &memoryIdempotencyStore{records: map[string]*entity.IdempotencyRecord{}, findErr: errors.New("database is locked")}
+		app := newApp(store, &status, &calls)
+
+		code, _, _ := send(app, "key-1", `{}`)
+		assert.Equal(t, fiber.StatusInternalServerError, code)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("requests without a key are not tracked", func(t *testing.T) {
+		status, calls := fiber.StatusCreated, 0
+		store := &memoryIdempotencyStore{records: map[string]*entity.IdempotencyRecord{}}
+		app := newApp(store, &status, &calls)
+
+		send(app, "", `{}`)
+		send(app, "", `{}`)
+		assert.Equal(t, 2, calls)
+		assert.Empty(t, store.records)
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		status, calls := fiber.StatusCreated, 0
+		app := newApp(&memoryIdempotencyStore{records: map[string]*entity.IdempotencyRecord{}}, &status, &calls)
+
+		code, _, _ := send(app, strings.Repeat("k", 256), `{}`)
+		assert.Equal(t, fiber.StatusBadRequest, code)
+		assert.Equal(t, 0, calls)
+	})
+}
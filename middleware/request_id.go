@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"regexp"
+
+	"itmx_test/logging"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const requestIDLocalKey = "request_id"
+
+// validRequestID keeps an inbound request ID only when it cannot forge log lines
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestIDMiddleware identifies every request by the X-Request-ID it was sent with, or a new
+// UUID, and echoes it in the response. The logger of c.UserContext() carries the ID from then on.
+func RequestIDMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(fiber.HeaderXRequestID)
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewString()
+		} else {
+			// fiber reuses the request buffers, the ID outlives the request in logs
+			id = utils.CopyString(id)
+		}
+
+		c.Locals(requestIDLocalKey, id)
+		c.Set(fiber.HeaderXRequestID, id)
+		c.SetUserContext(logging.WithFields(c.UserContext(), logrus.Fields{
+			"request_id": id,
+		}))
+
+		return c.Next()
+	}
+}
+
+// RequestID returns the ID RequestIDMiddleware gave the request
+func RequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(requestIDLocalKey).(string)
+	return id
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Use(RequestIDMiddleware())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(RequestID(c))
+	})
+
+	request := func(id string) (string, string) {
+		req := httptest.NewRequest("GET", "/", nil)
+		if id != "" {
+			req.Header.Set(fiber.HeaderXRequestID, id)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		body := make([]byte, 128)
+		n, _ := resp.Body.Read(body)
+		return resp.Header.Get(fiber.HeaderXRequestID), string(body[:n])
+	}
+
+	t.Run("honours the inbound id", func(t *testing.T) {
+		header, body := request("req-1")
+		assert.Equal(t, "req-1", header)
+		assert.Equal(t, "req-1", body)
+	})
+
+	t.Run("generates one when missing", func(t *testing.T) {
+		header, body := request("")
+		assert.NoError(t, uuid.Validate(header))
+		assert.Equal(t, header, body)
+	})
+
+	t.Run("replaces an id that could forge log lines", func(t *testing.T) {
+		header, _ := request("req 1\" level=error")
+		assert.NotEqual(t, "req 1\" level=error", header)
+		assert.NoError(t, uuid.Validate(header))
+	})
+}
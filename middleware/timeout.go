@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RouteTimeout overrides the default timeout for the routes under Path. An empty Method
+// matches every method, a ":name" segment matches any single path segment and a zero
+// Timeout disables the deadline.
+type RouteTimeout struct {
+	Method  string
+	Path    string
+	Timeout time.Duration
+}
+
+type TimeoutConfig struct {
+	Default time.Duration
+	Routes  []RouteTimeout
+}
+
+// TimeoutMiddleware puts a deadline on the request context (c.UserContext()). Usecases and
+// repositories run their database work with that context, so it is cancelled once the deadline
+// passes and the error handler answers 504. The deadline is the only thing that cancels it:
+// fasthttp does not report a client that disconnects mid-request, and the work then runs on
+// until it finishes or the deadline passes.
+func TimeoutMiddleware(config TimeoutConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		timeout := config.timeoutFor(c.Method(), c.Path())
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
+// timeoutFor returns the timeout of the most specific matching route, or the default
+func (tc TimeoutConfig) timeoutFor(method, path string) time.Duration {
+	timeout := tc.Default
+	matched := -1
+
+	segments := splitPath(path)
+	for _, route := range tc.Routes {
+		if route.Method != "" && !strings.EqualFold(route.Method, method) {
+			continue
+		}
+
+		routeSegments := splitPath(route.Path)
+		if len(routeSegments) <= matched || !matchSegments(routeSegments, segments) {
+			continue
+		}
+
+		timeout = route.Timeout
+		matched = len(routeSegments)
+	}
+
+	return timeout
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
+
+// matchSegments reports whether pattern is a prefix of path, segment by segment
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) > len(path) {
+		return false
+	}
+	for i, segment := range pattern {
+		if !strings.HasPrefix(segment, ":") && segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"itmx_test/domain"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutFor(t *testing.T) {
+	config := TimeoutConfig{
+		Default: 10 * time.Second,
+		Routes: []RouteTimeout{
+			{Path: "/customers", Timeout: 5 * time.Second},
+			{Method: "GET", Path: "/customers/export", Timeout: 0},
+			{Method: "POST", Path: "/customers/import", Timeout: 10 * time.Minute},
+			{Path: "/customers/:id/history", Timeout: time.Second},
+		},
+	}
+
+	assert.Equal(t, 10*time.Second, config.timeoutFor("GET", "/ping"))
+	assert.Equal(t, 5*time.Second, config.timeoutFor("GET", "/customers"))
+	assert.Equal(t, 5*time.Second, config.timeoutFor("GET", "/customers/123"))
+	assert.Equal(t, time.Duration(0), config.timeoutFor("GET", "/customers/export"))
+	assert.Equal(t, 10*time.Minute, config.timeoutFor("post", "/customers/import/"))
+	assert.Equal(t, 5*time.Second, config.timeoutFor("GET", "/customers/import"))
+	assert.Equal(t, time.Second, config.timeoutFor("GET", "/customers/123/history"))
+	assert.Equal(t, 10*time.Second, config.timeoutFor("GET", "/customersx"))
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Use(TimeoutMiddleware(TimeoutConfig{
+		Default: 20 * time.Millisecond,
+		Routes:  []RouteTimeout{{Path: "/unbounded", Timeout: 0}},
+	}))
+
+	slow := func(c *fiber.Ctx) error {
+		select {
+		case <-c.UserContext().Done():
+			err := c.UserContext().Err()
+			return c.Status(domain.GetStatusCode(err)).SendString(err.Error())
+		case <-time.After(100 * time.Millisecond):
+			return c.SendStatus(fiber.StatusOK)
+		}
+	}
+	app.Get("/slow", slow)
+	app.Get("/unbounded", slow)
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/slow", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusGatewayTimeout, resp.StatusCode)
+	})
+
+	t.Run("route without deadline", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/unbounded", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,21 @@
+package migration
+
+import "gorm.io/gorm"
+
+func init() {
+	register(6, "add_missing_customer_version", addMissingCustomerVersion, func(tx *gorm.DB) error {
+		// 0001 creates the column on new databases, rolling back must not drop it there
+		return nil
+	})
+}
+
+// addMissingCustomerVersion adopts databases whose customers table was created by AutoMigrate
+// before optimistic locking added the version column. 0001 skips the existing table, so the
+// column is added here when it is missing. Not every supported database has ADD COLUMN IF NOT
+// EXISTS, hence a Go migration.
+func addMissingCustomerVersion(tx *gorm.DB) error {
+	if tx.Migrator().HasColumn("customers", "version") {
+		return nil
+	}
+	return tx.Exec("ALTER TABLE customers ADD COLUMN version BIGINT NOT NULL DEFAULT 1").Error
+}
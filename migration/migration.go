@@ -0,0 +1,533 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql
+var migrationFiles embed.FS
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration has been modified")
+	ErrUnknownMigration = errors.New("applied migration is missing from this build")
+	ErrLocked           = errors.New("migrations are locked by another process")
+)
+
+const (
+	migrationTable = "schema_migrations"
+	lockTable      = "schema_migrations_lock"
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change, written as SQL scripts or, for changes SQL cannot
+// express portably, as Go functions. Checksum is the sha256 of the up script, or of the name
+// of a Go migration. It is stored when the migration is applied and verified on every later run.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+
+	UpFunc   func(tx *gorm.DB) error
+	DownFunc func(tx *gorm.DB) error
+}
+
+// goMigrations are the Go migrations of every dialect, added by register
+var goMigrations []*Migration
+
+func register(version int64, name string, up, down func(tx *gorm.DB) error) {
+	sum := sha256.Sum256([]byte("go:" + name))
+	goMigrations = append(goMigrations, &Migration{
+		Version:  version,
+		Name:     name,
+		Checksum: hex.EncodeToString(sum[:]),
+		UpFunc:   up,
+		DownFunc: down,
+	})
+}
+
+// Status is the state of one migration in the database
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return migrationTable
+}
+
+type schemaMigrationLock struct {
+	ID       int `gorm:"primaryKey;autoIncrement:false"`
+	Owner    string
+	LockedAt time.Time
+}
+
+func (schemaMigrationLock) TableName() string {
+	return lockTable
+}
+
+// Migrator applies the embedded migrations of the database's dialect. Runs are serialized
+// across processes by a row in schema_migrations_lock.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []*Migration
+
+	// LockTimeout is how long to wait for another process to release the lock
+	LockTimeout time.Duration
+	// StaleLockAfter is the age after which a lock is considered abandoned and taken over
+	StaleLockAfter time.Duration
+	// RenewLockEvery is how often a running migrator refreshes its lock so that it never looks
+	// stale to another process. It must be well below StaleLockAfter.
+	RenewLockEvery time.Duration
+}
+
+func New(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Load(migrationFiles, path.Join("sql", db.Dialector.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := map[int64]bool{}
+	for _, migration := range migrations {
+		versions[migration.Version] = true
+	}
+	for _, migration := range goMigrations {
+		if versions[migration.Version] {
+			return nil, fmt.Errorf("migration %d is defined twice", migration.Version)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return &Migrator{
+		db:             db,
+		migrations:     migrations,
+		LockTimeout:    time.Minute,
+		StaleLockAfter: 10 * time.Minute,
+		RenewLockEvery: time.Minute,
+	}, nil
+}
+
+// Load reads the NNNN_name.up.sql and NNNN_name.down.sql scripts of dir, ordered by version
+func Load(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			sum := sha256.Sum256(content)
+			migration.Up = string(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Checksum == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrations returns every known migration, oldest first
+func (m *Migrator) Migrations() []*Migration {
+	return m.migrations
+}
+
+// Up applies every pending migration in order and returns the ones it applied
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	var applied []*Migration
+
+	err := m.withLock(ctx, func() error {
+		pending, err := m.pending(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range pending {
+			if err := m.apply(ctx, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down rolls back the last steps applied migrations, newest first, and returns the ones it
+// rolled back
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var reverted []*Migration
+
+	err := m.withLock(ctx, func() error {
+		applied, err := m.verified(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.revert(ctx, migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.verified(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet, oldest first
+func (m *Migrator) Pending(ctx context.Context) ([]*Migration, error) {
+	return m.pending(ctx)
+}
+
+func (m *Migrator) pending(ctx context.Context) ([]*Migration, error) {
+	applied, err := m.verified(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []*Migration{}
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// verified loads the applied migrations and checks each one still matches its script
+func (m *Migrator) verified(ctx context.Context) (map[int64]*schemaMigration, error) {
+	if err := m.ensureTables(ctx); err != nil {
+		return nil, err
+	}
+
+	records := []*schemaMigration{}
+	if err := m.db.WithContext(ctx).Order("version asc").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	known := map[int64]*Migration{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	applied := map[int64]*schemaMigration{}
+	for _, record := range records {
+		migration, ok := known[record.Version]
+		if !ok {
+			return nil, fmt.Errorf("migration %d_%s: %w", record.Version, record.Name, ErrUnknownMigration)
+		}
+		if migration.Checksum != record.Checksum {
+			return nil, fmt.Errorf("migration %d_%s: %w", record.Version, record.Name, ErrChecksumMismatch)
+		}
+		applied[record.Version] = record
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) ensureTables(ctx context.Context) error {
+	db := m.db.WithContext(ctx)
+
+	if err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + migrationTable + ` (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum VARCHAR(64) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`).Error; err != nil {
+		return fmt.Errorf("create %s: %w", migrationTable, err)
+	}
+
+	if err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + lockTable + ` (
+		id INTEGER NOT NULL PRIMARY KEY,
+		owner VARCHAR(255) NOT NULL,
+		locked_at TIMESTAMP NOT NULL
+	)`).Error; err != nil {
+		return fmt.Errorf("create %s: %w", lockTable, err)
+	}
+
+	return nil
+}
+
+// apply runs the up script and records it in one transaction. MySQL commits DDL implicitly,
+// so there a failed migration can leave its earlier statements behind.
+func (m *Migrator) apply(ctx context.Context, migration *Migration) error {
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := run(tx, migration.Up, migration.UpFunc); err != nil {
+			return err
+		}
+
+		return tx.Create(&schemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Checksum:  migration.Checksum,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("apply migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) revert(ctx context.Context, migration *Migration) error {
+	if strings.TrimSpace(migration.Down) == "" && migration.DownFunc == nil {
+		return fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+	}
+
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := run(tx, migration.Down, migration.DownFunc); err != nil {
+			return err
+		}
+
+		return tx.Where("version = ?", migration.Version).Delete(&schemaMigration{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("revert migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func run(tx *gorm.DB, script string, fn func(tx *gorm.DB) error) error {
+	if fn != nil {
+		return fn(tx)
+	}
+
+	for _, stmt := range Split(script) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withLock runs fn while holding the migration lock. The lock is renewed every RenewLockEvery
+// while fn runs, so a lock older than StaleLockAfter is assumed to belong to a crashed process
+// and is taken over.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	if err := m.ensureTables(ctx); err != nil {
+		return err
+	}
+
+	owner := lockOwner()
+	db := m.db.WithContext(ctx)
+	deadline := time.Now().Add(m.LockTimeout)
+
+	for {
+		err := db.Create(&schemaMigrationLock{ID: 1, Owner: owner, LockedAt: time.Now().UTC()}).Error
+		if err == nil {
+			break
+		}
+
+		held := &schemaMigrationLock{}
+		if findErr := db.Where("id = ?", 1).Limit(1).Find(held).Error; findErr != nil {
+			return findErr
+		}
+		if held.ID == 0 {
+			// the insert failed for another reason than a held lock
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+
+		if time.Since(held.LockedAt) > m.StaleLockAfter {
+			if err := db.Where("id = ? AND owner = ?", 1, held.Owner).Delete(&schemaMigrationLock{}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: held by %s since %s", ErrLocked, held.Owner, held.LockedAt.Format(time.RFC3339))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+
+	defer m.db.Where("id = ? AND owner = ?", 1, owner).Delete(&schemaMigrationLock{})
+
+	stop := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		m.renewLock(owner, stop)
+	}()
+	defer func() {
+		close(stop)
+		<-renewed
+	}()
+
+	return fn()
+}
+
+// renewLock moves the lock's locked_at forward every RenewLockEvery until stop is closed. A
+// failed renewal is retried on the next tick.
+func (m *Migrator) renewLock(owner string, stop <-chan struct{}) {
+	if m.RenewLockEvery <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.RenewLockEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.db.Model(&schemaMigrationLock{}).
+				Where("id = ? AND owner = ?", 1, owner).
+				Update("locked_at", time.Now().UTC())
+		}
+	}
+}
+
+func lockOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+}
+
+// Split breaks a script into statements. A statement ends with a semicolon at the end of a
+// line, except between "-- +begin" and "-- +end" markers, which wrap bodies such as triggers
+// that contain semicolons themselves. Statements made only of comments are dropped.
+func Split(script string) []string {
+	statements := []string{}
+	var current strings.Builder
+	block := false
+
+	flush := func() {
+		stmt := strings.TrimSpace(current.String())
+		current.Reset()
+		if hasCode(stmt) {
+			statements = append(statements, strings.TrimSuffix(stmt, ";"))
+		}
+	}
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch trimmed {
+		case "-- +begin":
+			flush()
+			block = true
+			continue
+		case "-- +end":
+			block = false
+			flush()
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if !block && strings.HasSuffix(trimmed, ";") && !strings.HasPrefix(trimmed, "--") {
+			flush()
+		}
+	}
+	flush()
+
+	return statements
+}
+
+func hasCode(stmt string) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return true
+		}
+	}
+	return false
+}
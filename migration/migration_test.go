@@ -0,0 +1,291 @@
+package migration
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"itmx_test/service/entity"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	path := filepath.Join(t.TempDir(), "itmx.sqlite")
+	db, err := gorm.Open(sqlite.Open(path+"?_pragma=busy_timeout(5000)"), &gorm.Config{})
+	require.NoError(t, err)
+
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestSplit(t *testing.T) {
+	script := `-- leading comment
+CREATE TABLE a (id integer);
+CREATE INDEX idx_a
+	ON a (id);
+
+-- +begin
+CREATE TRIGGER a_ai AFTER INSERT ON a BEGIN
+	SELECT 1;
+	SELECT 2;
+END;
+-- +end
+-- a comment only statement;
+`
+
+	statements := Split(script)
+	assert.Len(t, statements, 3)
+	assert.Equal(t, "-- leading comment\nCREATE TABLE a (id integer)", statements[0])
+	assert.Equal(t, "CREATE INDEX idx_a\n\tON a (id)", statements[1])
+	assert.Contains(t, statements[2], "SELECT 2;\nEND")
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"sql/0002_second.up.sql":   {Data: []byte("SELECT 2;")},
+			"sql/0001_first.up.sql":    {Data: []byte("SELECT 1;")},
+			"sql/0001_first.down.sql":  {Data: []byte("SELECT -1;")},
+			"sql/README.md":            {Data: []byte("ignored")},
+			"sql/0003_third.down.sql~": {Data: []byte("ignored")},
+		}
+
+		migrations, err := Load(fsys, "sql")
+		assert.NoError(t, err)
+		assert.Len(t, migrations, 2)
+		assert.Equal(t, int64(1), migrations[0].Version)
+		assert.Equal(t, "first", migrations[0].Name)
+		assert.Equal(t, "SELECT -1;", migrations[0].Down)
+		assert.Len(t, migrations[0].Checksum, 64)
+		assert.Equal(t, int64(2), migrations[1].Version)
+	})
+
+	t.Run("missing up script", func(t *testing.T) {
+		fsys := fstest.MapFS{"sql/0001_first.down.sql": {Data: []byte("SELECT 1;")}}
+
+		_, err := Load(fsys, "sql")
+		assert.ErrorContains(t, err, "no up script")
+	})
+
+	t.Run("embedded dialects", func(t *testing.T) {
+		for _, dialect := range []string{"sqlite", "mysql", "postgres"} {
+			migrations, err := Load(migrationFiles, "sql/"+dialect)
+			assert.NoError(t, err, dialect)
+			assert.Len(t, migrations, 5, dialect)
+		}
+	})
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("up, status and down", func(t *testing.T) {
+		db := openDB(t)
+		migrator, err := New(db)
+		require.NoError(t, err)
+
+		applied, err := migrator.Up(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, applied, len(migrator.Migrations()))
+
+		applied, err = migrator.Up(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, applied)
+
+		statuses, err := migrator.Status(ctx)
+		assert.NoError(t, err)
+		for _, status := range statuses {
+			assert.True(t, status.Applied)
+			assert.NotNil(t, status.AppliedAt)
+		}
+
+		reverted, err := migrator.Down(ctx, 2)
+		assert.NoError(t, err)
+		assert.Len(t, reverted, 2)
+		assert.Equal(t, "add_missing_customer_version", reverted[0].Name)
+		assert.Equal(t, "create_idempotency_records", reverted[1].Name)
+		assert.False(t, db.Migrator().HasTable("idempotency_records"))
+
+		pending, err := migrator.Pending(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, pending, 2)
+
+		reverted, err = migrator.Down(ctx, 100)
+		assert.NoError(t, err)
+		assert.Len(t, reverted, len(migrator.Migrations())-2)
+		assert.False(t, db.Migrator().HasTable("customers"))
+		assert.False(t, db.Migrator().HasTable("customer_search"))
+	})
+
+	t.Run("schema matches the entities", func(t *testing.T) {
+		db := openDB(t)
+		migrator, err := New(db)
+		require.NoError(t, err)
+		_, err = migrator.Up(ctx)
+		require.NoError(t, err)
+
+		customer := &entity.Customer{ID: "1", Name: "Alice", Age: 30}
+		assert.NoError(t, db.Create(customer).Error)
+		assert.Equal(t, 1, customer.Version)
+
+		var ids []string
+		assert.NoError(t, db.Raw("SELECT customer_id FROM customer_search WHERE customer_search MATCH ?", "alice").Scan(&ids).Error)
+		assert.Equal(t, []string{"1"}, ids)
+
+		audit := &entity.CustomerAudit{CustomerID: "1", Operation: entity.AuditCreate, Changes: map[string]entity.FieldChange{"name": {To: "Alice"}}}
+		assert.NoError(t, db.Create(audit).Error)
+		assert.Error(t, db.Delete(audit).Error)
+
+		subscription := &entity.WebhookSubscription{ID: "s1", URL: "http://example.com", Secret: "secret", Events: []string{entity.EventCustomerCreated}, Active: true}
+		assert.NoError(t, db.Create(subscription).Error)
+
+		delivery := &entity.WebhookDelivery{SubscriptionID: "s1", EventID: 1, EventType: entity.EventCustomerCreated, Payload: "{}", Status: entity.DeliveryPending}
+		assert.NoError(t, db.Create(delivery).Error)
+		assert.NoError(t, db.Create(&entity.WebhookAttempt{DeliveryID: delivery.ID, StatusCode: 200}).Error)
+
+		record := &entity.IdempotencyRecord{Key: "k", Fingerprint: "f", Body: []byte("{}"), ExpiresAt: time.Now().Add(time.Hour)}
+		assert.NoError(t, db.Create(record).Error)
+	})
+
+	t.Run("adopts a schema created by AutoMigrate", func(t *testing.T) {
+		db := openDB(t)
+		require.NoError(t, db.AutoMigrate(entity.Customer{}, entity.CustomerAudit{}))
+		require.NoError(t, db.Create(&entity.Customer{ID: "1", Name: "Alice", Age: 30}).Error)
+
+		migrator, err := New(db)
+		require.NoError(t, err)
+		_, err = migrator.Up(ctx)
+		assert.NoError(t, err)
+
+		var count int64
+		assert.NoError(t, db.Raw("SELECT count(*) FROM customer_search").Scan(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("adopts a schema created before the version column", func(t *testing.T) {
+		db := openDB(t)
+		require.NoError(t, db.Exec("CREATE TABLE `customers` (`id` text,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime DEFAULT null,`name` text,`age` integer,PRIMARY KEY (`id`))").Error)
+		require.NoError(t, db.Exec("INSERT INTO customers (id, name, age) VALUES ('1', 'Alice', 30)").Error)
+
+		migrator, err := New(db)
+		require.NoError(t, err)
+		_, err = migrator.Up(ctx)
+		assert.NoError(t, err)
+
+		customer := &entity.Customer{}
+		assert.NoError(t, db.First(customer, "id = ?", "1").Error)
+		assert.Equal(t, 1, customer.Version)
+		assert.NoError(t, db.Create(&entity.Customer{ID: "2", Name: "Bob", Age: 40}).Error)
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		db := openDB(t)
+		migrator, err := New(db)
+		require.NoError(t, err)
+		_, err = migrator.Up(ctx)
+		require.NoError(t, err)
+
+		require.NoError(t, db.Exec("UPDATE schema_migrations SET checksum = 'edited' WHERE version = 1").Error)
+
+		_, err = migrator.Up(ctx)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+		_, err = migrator.Status(ctx)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+
+	t.Run("unknown migration", func(t *testing.T) {
+		db := openDB(t)
+		migrator, err := New(db)
+		require.NoError(t, err)
+		_, err = migrator.Up(ctx)
+		require.NoError(t, err)
+
+		require.NoError(t, db.Create(&schemaMigration{Version: 9999, Name: "future", Checksum: "x", AppliedAt: time.Now()}).Error)
+
+		_, err = migrator.Pending(ctx)
+		assert.ErrorIs(t, err, ErrUnknownMigration)
+	})
+
+	t.Run("failed migration is rolled back", func(t *testing.T) {
+		db := openDB(t)
+		migrator, err := New(db)
+		require.NoError(t, err)
+		migrator.migrations = append(migrator.migrations, &Migration{
+			Version:  9999,
+			Name:     "broken",
+			Up:       "CREATE TABLE broken (id integer);\nNOT SQL;",
+			Checksum: "x",
+		})
+
+		_, err = migrator.Up(ctx)
+		assert.ErrorContains(t, err, "9999_broken")
+		assert.False(t, db.Migrator().HasTable("broken"))
+
+		pending, err := migrator.Pending(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, pending, 1)
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		db := openDB(t)
+		migrator, err := New(db)
+		require.NoError(t, err)
+		migrator.LockTimeout = 0
+
+		require.NoError(t, migrator.ensureTables(ctx))
+		require.NoError(t, db.Create(&schemaMigrationLock{ID: 1, Owner: "other", LockedAt: time.Now().UTC()}).Error)
+
+		_, err = migrator.Up(ctx)
+		assert.ErrorIs(t, err, ErrLocked)
+		assert.False(t, db.Migrator().HasTable("customers"))
+	})
+
+	t.Run("stale lock is taken over", func(t *testing.T) {
+		db := openDB(t)
+		migrator, err := New(db)
+		require.NoError(t, err)
+
+		require.NoError(t, migrator.ensureTables(ctx))
+		require.NoError(t, db.Create(&schemaMigrationLock{ID: 1, Owner: "crashed", LockedAt: time.Now().Add(-time.Hour).UTC()}).Error)
+
+		_, err = migrator.Up(ctx)
+		assert.NoError(t, err)
+
+		var locks int64
+		assert.NoError(t, db.Model(&schemaMigrationLock{}).Count(&locks).Error)
+		assert.Equal(t, int64(0), locks)
+	})
+	t.Run("running migrator renews its lock", func(t *testing.T) {
+		db := openDB(t)
+		migrator, err := New(db)
+		require.NoError(t, err)
+		migrator.StaleLockAfter = 100 * time.Millisecond
+		migrator.RenewLockEvery = 20 * time.Millisecond
+
+		other, err := New(db)
+		require.NoError(t, err)
+		other.LockTimeout = 0
+		other.StaleLockAfter = migrator.StaleLockAfter
+
+		err = migrator.withLock(ctx, func() error {
+			time.Sleep(3 * migrator.StaleLockAfter)
+
+			_, err := other.Up(ctx)
+			assert.ErrorIs(t, err, ErrLocked)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.False(t, db.Migrator().HasTable("customers"))
+
+		var locks int64
+		assert.NoError(t, db.Model(&schemaMigrationLock{}).Count(&locks).Error)
+		assert.Equal(t, int64(0), locks)
+	})
+}
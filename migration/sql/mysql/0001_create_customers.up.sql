@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `customers` (
+	`id` varchar(191) NOT NULL,
+	`created_at` datetime(3) NULL,
+	`updated_at` datetime(3) NULL,
+	`deleted_at` datetime(3) NULL,
+	`name` longtext,
+	`age` bigint,
+	`version` bigint NOT NULL DEFAULT 1,
+	PRIMARY KEY (`id`),
+	INDEX `idx_customers_deleted_at` (`deleted_at`)
+);
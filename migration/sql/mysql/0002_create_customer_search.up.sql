@@ -0,0 +1 @@
+-- the customer search index is SQLite only, MySQL searches customers with LIKE
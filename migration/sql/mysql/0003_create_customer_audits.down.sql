@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS customer_audits_no_delete;
+DROP TRIGGER IF EXISTS customer_audits_no_update;
+DROP TABLE IF EXISTS `customer_audits`;
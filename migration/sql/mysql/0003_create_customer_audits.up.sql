@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS `customer_audits` (
+	`id` bigint unsigned AUTO_INCREMENT,
+	`customer_id` varchar(191) NOT NULL,
+	`operation` varchar(32) NOT NULL,
+	`actor` varchar(255),
+	`request_id` varchar(255),
+	`changes` longtext,
+	`created_at` datetime(3) NULL,
+	PRIMARY KEY (`id`),
+	INDEX `idx_customer_audits_customer_id` (`customer_id`)
+);
+
+-- audit records are append-only
+DROP TRIGGER IF EXISTS customer_audits_no_update;
+
+CREATE TRIGGER customer_audits_no_update BEFORE UPDATE ON customer_audits FOR EACH ROW
+	SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'customer audit records are immutable';
+
+DROP TRIGGER IF EXISTS customer_audits_no_delete;
+
+CREATE TRIGGER customer_audits_no_delete BEFORE DELETE ON customer_audits FOR EACH ROW
+	SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'customer audit records are immutable';
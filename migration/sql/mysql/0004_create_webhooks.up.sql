@@ -0,0 +1,53 @@
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+	`id` bigint unsigned AUTO_INCREMENT,
+	`event_type` varchar(64) NOT NULL,
+	`customer_id` varchar(191),
+	`payload` longtext NOT NULL,
+	`created_at` datetime(3) NULL,
+	`processed_at` datetime(3) NULL,
+	PRIMARY KEY (`id`),
+	INDEX `idx_outbox_events_processed_at` (`processed_at`),
+	INDEX `idx_outbox_events_customer_id` (`customer_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `webhook_subscriptions` (
+	`id` varchar(191) NOT NULL,
+	`url` varchar(2048) NOT NULL,
+	`secret` varchar(255) NOT NULL,
+	`events` longtext,
+	`active` boolean NOT NULL DEFAULT true,
+	`created_at` datetime(3) NULL,
+	`updated_at` datetime(3) NULL,
+	PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+	`id` bigint unsigned AUTO_INCREMENT,
+	`subscription_id` varchar(191) NOT NULL,
+	`event_id` bigint unsigned NOT NULL,
+	`event_type` varchar(64) NOT NULL,
+	`payload` longtext NOT NULL,
+	`status` varchar(32) NOT NULL,
+	`attempts` bigint NOT NULL DEFAULT 0,
+	`next_attempt_at` datetime(3) NULL,
+	`last_error` longtext,
+	`created_at` datetime(3) NULL,
+	`updated_at` datetime(3) NULL,
+	PRIMARY KEY (`id`),
+	INDEX `idx_webhook_deliveries_subscription_id` (`subscription_id`),
+	INDEX `idx_webhook_deliveries_event_id` (`event_id`),
+	INDEX `idx_webhook_deliveries_status` (`status`),
+	INDEX `idx_webhook_deliveries_next_attempt_at` (`next_attempt_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `webhook_attempts` (
+	`id` bigint unsigned AUTO_INCREMENT,
+	`delivery_id` bigint unsigned NOT NULL,
+	`status_code` bigint,
+	`error` longtext,
+	`duration_ms` bigint,
+	`created_at` datetime(3) NULL,
+	PRIMARY KEY (`id`),
+	INDEX `idx_webhook_attempts_delivery_id` (`delivery_id`),
+	CONSTRAINT `fk_webhook_deliveries_attempt_log` FOREIGN KEY (`delivery_id`) REFERENCES `webhook_deliveries`(`id`)
+);
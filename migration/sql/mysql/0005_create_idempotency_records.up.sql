@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `idempotency_records` (
+	`idempotency_key` varchar(255) NOT NULL,
+	`fingerprint` varchar(64) NOT NULL,
+	`status_code` bigint NOT NULL DEFAULT 0,
+	`content_type` varchar(255),
+	`body` longblob,
+	`created_at` datetime(3) NULL,
+	`expires_at` datetime(3) NOT NULL,
+	PRIMARY KEY (`idempotency_key`),
+	INDEX `idx_idempotency_records_expires_at` (`expires_at`)
+);
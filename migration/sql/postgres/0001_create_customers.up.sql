@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS customers (
+	id text,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	name text,
+	age bigint,
+	version bigint NOT NULL DEFAULT 1,
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_customers_deleted_at ON customers (deleted_at);
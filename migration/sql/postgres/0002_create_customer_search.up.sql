@@ -0,0 +1 @@
+-- the customer search index is SQLite only, PostgreSQL searches customers with LIKE
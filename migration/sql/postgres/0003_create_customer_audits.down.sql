@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS customer_audits;
+DROP FUNCTION IF EXISTS customer_audits_immutable();
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS customer_audits (
+	id bigserial,
+	customer_id text NOT NULL,
+	operation text NOT NULL,
+	actor text,
+	request_id text,
+	changes text,
+	created_at timestamptz,
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_audits_customer_id ON customer_audits (customer_id);
+
+-- audit records are append-only
+-- +begin
+CREATE OR REPLACE FUNCTION customer_audits_immutable() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'customer audit records are immutable';
+END;
+$$ LANGUAGE plpgsql;
+-- +end
+
+DROP TRIGGER IF EXISTS customer_audits_no_change ON customer_audits;
+
+CREATE TRIGGER customer_audits_no_change BEFORE UPDATE OR DELETE ON customer_audits
+	FOR EACH ROW EXECUTE FUNCTION customer_audits_immutable();
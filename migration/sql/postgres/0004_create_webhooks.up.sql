@@ -0,0 +1,56 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+	id bigserial,
+	event_type text NOT NULL,
+	customer_id text,
+	payload text NOT NULL,
+	created_at timestamptz,
+	processed_at timestamptz,
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_processed_at ON outbox_events (processed_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_customer_id ON outbox_events (customer_id);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+	id text,
+	url text NOT NULL,
+	secret text NOT NULL,
+	events text,
+	active boolean NOT NULL DEFAULT true,
+	created_at timestamptz,
+	updated_at timestamptz,
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id bigserial,
+	subscription_id text NOT NULL,
+	event_id bigint NOT NULL,
+	event_type text NOT NULL,
+	payload text NOT NULL,
+	status text NOT NULL,
+	attempts bigint NOT NULL DEFAULT 0,
+	next_attempt_at timestamptz,
+	last_error text,
+	created_at timestamptz,
+	updated_at timestamptz,
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries (event_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+	id bigserial,
+	delivery_id bigint NOT NULL,
+	status_code bigint,
+	error text,
+	duration_ms bigint,
+	created_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_webhook_deliveries_attempt_log FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery_id ON webhook_attempts (delivery_id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS idempotency_records (
+	idempotency_key text,
+	fingerprint text NOT NULL,
+	status_code bigint NOT NULL DEFAULT 0,
+	content_type text,
+	body bytea,
+	created_at timestamptz,
+	expires_at timestamptz NOT NULL,
+	PRIMARY KEY (idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_records_expires_at ON idempotency_records (expires_at);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `customers` (
+	`id` text,
+	`created_at` datetime,
+	`updated_at` datetime,
+	`deleted_at` datetime DEFAULT null,
+	`name` text,
+	`age` integer,
+	`version` integer NOT NULL DEFAULT 1,
+	PRIMARY KEY (`id`)
+);
+
+CREATE INDEX IF NOT EXISTS `idx_customers_deleted_at` ON `customers`(`deleted_at`);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS customer_search_ad;
+DROP TRIGGER IF EXISTS customer_search_au;
+DROP TRIGGER IF EXISTS customer_search_ai;
+DROP TABLE IF EXISTS customer_search;
@@ -0,0 +1,26 @@
+-- full text index over customer names, kept in sync by triggers. Soft deleted rows are
+-- filtered at query time.
+CREATE VIRTUAL TABLE IF NOT EXISTS customer_search USING fts5(customer_id UNINDEXED, name, tokenize = 'unicode61 remove_diacritics 2');
+
+-- +begin
+CREATE TRIGGER IF NOT EXISTS customer_search_ai AFTER INSERT ON customers BEGIN
+	INSERT INTO customer_search(customer_id, name) VALUES (new.id, new.name);
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER IF NOT EXISTS customer_search_au AFTER UPDATE OF id, name ON customers BEGIN
+	DELETE FROM customer_search WHERE customer_id = old.id;
+	INSERT INTO customer_search(customer_id, name) VALUES (new.id, new.name);
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER IF NOT EXISTS customer_search_ad AFTER DELETE ON customers BEGIN
+	DELETE FROM customer_search WHERE customer_id = old.id;
+END;
+-- +end
+
+-- backfill customers created before the index existed
+INSERT INTO customer_search(customer_id, name)
+	SELECT id, name FROM customers WHERE id NOT IN (SELECT customer_id FROM customer_search);
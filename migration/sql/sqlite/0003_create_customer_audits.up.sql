@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS `customer_audits` (
+	`id` integer PRIMARY KEY AUTOINCREMENT,
+	`customer_id` text NOT NULL,
+	`operation` text NOT NULL,
+	`actor` text,
+	`request_id` text,
+	`changes` text,
+	`created_at` datetime
+);
+
+CREATE INDEX IF NOT EXISTS `idx_customer_audits_customer_id` ON `customer_audits`(`customer_id`);
+
+-- audit records are append-only
+-- +begin
+CREATE TRIGGER IF NOT EXISTS customer_audits_no_update BEFORE UPDATE ON customer_audits BEGIN
+	SELECT RAISE(ABORT, 'customer audit records are immutable');
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER IF NOT EXISTS customer_audits_no_delete BEFORE DELETE ON customer_audits BEGIN
+	SELECT RAISE(ABORT, 'customer audit records are immutable');
+END;
+-- +end
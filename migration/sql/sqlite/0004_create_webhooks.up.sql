@@ -0,0 +1,53 @@
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+	`id` integer PRIMARY KEY AUTOINCREMENT,
+	`event_type` text NOT NULL,
+	`customer_id` text,
+	`payload` text NOT NULL,
+	`created_at` datetime,
+	`processed_at` datetime
+);
+
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_processed_at` ON `outbox_events`(`processed_at`);
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_customer_id` ON `outbox_events`(`customer_id`);
+
+CREATE TABLE IF NOT EXISTS `webhook_subscriptions` (
+	`id` text,
+	`url` text NOT NULL,
+	`secret` text NOT NULL,
+	`events` text,
+	`active` numeric NOT NULL DEFAULT true,
+	`created_at` datetime,
+	`updated_at` datetime,
+	PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+	`id` integer PRIMARY KEY AUTOINCREMENT,
+	`subscription_id` text NOT NULL,
+	`event_id` integer NOT NULL,
+	`event_type` text NOT NULL,
+	`payload` text NOT NULL,
+	`status` text NOT NULL,
+	`attempts` integer NOT NULL DEFAULT 0,
+	`next_attempt_at` datetime,
+	`last_error` text,
+	`created_at` datetime,
+	`updated_at` datetime
+);
+
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_subscription_id` ON `webhook_deliveries`(`subscription_id`);
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_event_id` ON `webhook_deliveries`(`event_id`);
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_status` ON `webhook_deliveries`(`status`);
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_next_attempt_at` ON `webhook_deliveries`(`next_attempt_at`);
+
+CREATE TABLE IF NOT EXISTS `webhook_attempts` (
+	`id` integer PRIMARY KEY AUTOINCREMENT,
+	`delivery_id` integer NOT NULL,
+	`status_code` integer,
+	`error` text,
+	`duration_ms` integer,
+	`created_at` datetime,
+	CONSTRAINT `fk_webhook_deliveries_attempt_log` FOREIGN KEY (`delivery_id`) REFERENCES `webhook_deliveries`(`id`)
+);
+
+CREATE INDEX IF NOT EXISTS `idx_webhook_attempts_delivery_id` ON `webhook_attempts`(`delivery_id`);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `idempotency_records` (
+	`idempotency_key` text,
+	`fingerprint` text NOT NULL,
+	`status_code` integer NOT NULL DEFAULT 0,
+	`content_type` text,
+	`body` blob,
+	`created_at` datetime,
+	`expires_at` datetime NOT NULL,
+	PRIMARY KEY (`idempotency_key`)
+);
+
+CREATE INDEX IF NOT EXISTS `idx_idempotency_records_expires_at` ON `idempotency_records`(`expires_at`);
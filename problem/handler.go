@@ -0,0 +1,39 @@
+package problem
+
+import (
+	"net/http"
+
+	"itmx_test/domain"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CatalogueEntry describes one error code a response may carry
+type CatalogueEntry struct {
+	Code    string `json:"code"`
+	Status  int    `json:"status"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// NewHandler registers GET /errors, the catalogue of every declared error code
+func NewHandler(f *fiber.App) {
+	f.Get("/errors", Catalogue)
+}
+
+func Catalogue(c *fiber.Ctx) error {
+	errs := domain.Errors()
+	entries := make([]CatalogueEntry, len(errs))
+	for i, err := range errs {
+		entries[i] = CatalogueEntry{
+			Code:    err.Code,
+			Status:  err.Status,
+			Title:   http.StatusText(err.Status),
+			Message: err.Message,
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": entries,
+	})
+}
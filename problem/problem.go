@@ -0,0 +1,180 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"itmx_test/domain"
+
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ContentType is the media type of a problem document
+const ContentType = "application/problem+json"
+
+// FieldError is the failure of one field of the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem document. It is an error, so handlers and middleware return
+// it and the error handler renders it.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Code     string       `json:"code"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+
+	// Extensions are extra members of the document, as RFC 7807 allows
+	Extensions map[string]interface{} `json:"-"`
+
+	// cause is the error the problem was made from, logged but not rendered
+	cause error
+}
+
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// FromDomain is the problem of a declared domain error, with a more specific detail than its
+// message when detail is set
+func FromDomain(err *domain.Error, detail string) *Problem {
+	if detail == "" {
+		detail = err.Message
+	}
+	p := New(err.Status, err.Code, detail)
+	p.cause = err
+	return p
+}
+
+// BadRequest is the problem of a request parameter that is not valid
+func BadRequest(detail string) *Problem {
+	return FromDomain(domain.ErrBadParamInput, detail)
+}
+
+// MalformedBody is the problem of a request body that could not be parsed
+func MalformedBody(err error) *Problem {
+	p := FromDomain(domain.ErrMalformedBody, err.Error())
+	p.cause = err
+	return p
+}
+
+// Validation is the problem of a request failing validation, one FieldError per field
+func Validation(errs validator.ValidationErrors) *Problem {
+	p := FromDomain(domain.ErrValidationFailed, "")
+	p.Errors = FieldErrors(errs, "")
+	p.cause = errs
+	return p
+}
+
+// FieldErrors lists the failed fields of errs, their names prefixed with prefix
+func FieldErrors(errs validator.ValidationErrors, prefix string) []FieldError {
+	fieldErrors := make([]FieldError, 0, len(errs))
+	for _, fieldError := range errs {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   prefix + fieldError.Field(),
+			Code:    fieldError.Tag(),
+			Message: fmt.Sprintf("%s is %s", fieldError.Field(), fieldError.Tag()),
+		})
+	}
+	return fieldErrors
+}
+
+// FromError turns any error into a problem. A domain error, wrapped or not, gives its code,
+// status and message, other errors are internal errors whose text is only logged.
+func FromError(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return Validation(validationErrors)
+	}
+
+	var fieldErr *domain.FieldError
+	if errors.As(err, &fieldErr) {
+		p = FromDomain(fieldErr.Err, "")
+		p.Errors = []FieldError{{Field: fieldErr.Field, Code: fieldErr.Err.Code, Message: fieldErr.Err.Message}}
+		p.cause = err
+		return p
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return New(fiberErr.Code, codeFor(fiberErr.Code), fiberErr.Message)
+	}
+
+	p = FromDomain(domain.Lookup(err), "")
+	p.cause = err
+	return p
+}
+
+// codeFor is the code of an error fiber reports by status only, the code of the domain error
+// of the same status and meaning or the status text in snake case
+func codeFor(status int) string {
+	switch status {
+	case http.StatusNotFound:
+		return domain.ErrNotFound.Code
+	case http.StatusBadRequest:
+		return domain.ErrBadParamInput.Code
+	}
+	return strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
+
+// With adds an extension member to the document
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = map[string]interface{}{}
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.cause != nil {
+		return p.cause.Error()
+	}
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+func (p *Problem) Unwrap() error {
+	return p.cause
+}
+
+// MarshalJSON writes the extensions next to the standard members
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type document Problem
+	standard, err := json.Marshal((*document)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return standard, err
+	}
+
+	members := map[string]interface{}{}
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+	if err := json.Unmarshal(standard, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
@@ -0,0 +1,112 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"itmx_test/domain"
+
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromError(t *testing.T) {
+	t.Run("domain error", func(t *testing.T) {
+		p := FromError(domain.ErrNotFound)
+		assert.Equal(t, fiber.StatusNotFound, p.Status)
+		assert.Equal(t, "not_found", p.Code)
+		assert.Equal(t, "Not Found", p.Title)
+		assert.Equal(t, domain.ErrNotFound.Error(), p.Detail)
+		assert.ErrorIs(t, p, domain.ErrNotFound)
+	})
+
+	t.Run("wrapped domain error", func(t *testing.T) {
+		p := FromError(fmt.Errorf("find customer 42: %w", domain.ErrNotFound))
+		assert.Equal(t, fiber.StatusNotFound, p.Status)
+		assert.Equal(t, domain.ErrNotFound.Code, p.Code)
+		// the client gets the safe message, the logs the whole chain
+		assert.Equal(t, domain.ErrNotFound.Message, p.Detail)
+		assert.Equal(t, "find customer 42: your requested Item is not found", p.Error())
+	})
+
+	t.Run("internal error hides its detail", func(t *testing.T) {
+		cause := errors.New("dial tcp 10.0.0.1:3306: connection refused")
+		p := FromError(cause)
+		assert.Equal(t, fiber.StatusInternalServerError, p.Status)
+		assert.Equal(t, domain.ErrInternalServerError.Code, p.Code)
+		assert.Equal(t, domain.ErrInternalServerError.Message, p.Detail)
+		// the cause is still there for the logs
+		assert.Equal(t, cause.Error(), p.Error())
+	})
+
+	t.Run("validation errors", func(t *testing.T) {
+		type body struct {
+			Name string `validate:"required"`
+			Age  int    `validate:"min=1"`
+		}
+		err := validator.New().Struct(body{})
+
+		p := FromError(fmt.Errorf("validate: %w", err))
+		assert.Equal(t, fiber.StatusBadRequest, p.Status)
+		assert.Equal(t, domain.ErrValidationFailed.Code, p.Code)
+		assert.Equal(t, []FieldError{
+			{Field: "Name", Code: "required", Message: "Name is required"},
+			{Field: "Age", Code: "min", Message: "Age is min"},
+		}, p.Errors)
+	})
+
+	t.Run("fiber error", func(t *testing.T) {
+		p := FromError(fiber.ErrMethodNotAllowed)
+		assert.Equal(t, fiber.StatusMethodNotAllowed, p.Status)
+		assert.Equal(t, "method_not_allowed", p.Code)
+
+		p = FromError(fiber.NewError(fiber.StatusNotFound, "Cannot GET /nowhere"))
+		assert.Equal(t, domain.ErrNotFound.Code, p.Code)
+		assert.Equal(t, "Cannot GET /nowhere", p.Detail)
+	})
+
+	t.Run("problem", func(t *testing.T) {
+		original := BadRequest("limit is not a number")
+		assert.Same(t, original, FromError(fmt.Errorf("wrapped: %w", original)))
+	})
+}
+
+func TestMarshalJSON(t *testing.T) {
+	p := BadRequest("bad input")
+	p.Instance = "req-1"
+
+	body, err := json.Marshal(p)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"code":"bad_param_input","detail":"bad input","instance":"req-1"}`, string(body))
+
+	// extensions sit next to the standard members, which they cannot replace
+	p.With("report", map[string]int{"created": 1}).With("status", 200)
+	body, err = json.Marshal(p)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"code":"bad_param_input","detail":"bad input","instance":"req-1","report":{"created":1}}`, string(body))
+}
+
+func TestCatalogue(t *testing.T) {
+	f := fiber.New()
+	NewHandler(f)
+
+	resp, err := f.Test(httptest.NewRequest("GET", "/errors", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Data []CatalogueEntry `json:"data"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Len(t, body.Data, len(domain.Errors()))
+	assert.Contains(t, body.Data, CatalogueEntry{
+		Code:    "version_mismatch",
+		Status:  fiber.StatusPreconditionFailed,
+		Title:   "Precondition Failed",
+		Message: domain.ErrVersionMismatch.Message,
+	})
+}
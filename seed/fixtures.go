@@ -0,0 +1,125 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"itmx_test/service/entity"
+	"itmx_test/validation"
+
+	"github.com/spf13/viper"
+)
+
+// BaseSet is the fixture set loaded for every environment, before the environment's own set
+const BaseSet = "base"
+
+var fixtureExtensions = map[string]bool{".yml": true, ".yaml": true, ".json": true}
+
+// CustomerFixture is one customer of a fixture file. The name is its natural key.
+type CustomerFixture struct {
+	Name string `mapstructure:"name" json:"name" validate:"required,max=100"`
+	Age  int    `mapstructure:"age" json:"age" validate:"required,numeric,min=1,max=110"`
+}
+
+// LoadFile reads the customers list of a YAML or JSON fixture file, the format is picked from
+// the file extension
+func LoadFile(file string) ([]CustomerFixture, error) {
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read fixture file %s: %w", file, err)
+	}
+
+	fixtures := []CustomerFixture{}
+	if err := v.UnmarshalKey(`customers`, &fixtures); err != nil {
+		return nil, fmt.Errorf("read fixture file %s: %w", file, err)
+	}
+
+	for i, fixture := range fixtures {
+		if err := validation.Validate(fixture); err != nil {
+			return nil, fmt.Errorf("fixture file %s, customer %d: %v", file, i+1, validation.ErrorResponse(err))
+		}
+	}
+
+	return fixtures, nil
+}
+
+// LoadSet reads the fixture files of dir/base and then of dir/<env>, each in file name order.
+// A missing set is skipped, and a customer listed again replaces the earlier entry.
+func LoadSet(dir, env string) ([]CustomerFixture, error) {
+	var files []string
+	for _, set := range []string{BaseSet, env} {
+		if set == "" {
+			continue
+		}
+
+		entries, err := os.ReadDir(filepath.Join(dir, set))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		names := []string{}
+		for _, entry := range entries {
+			if !entry.IsDir() && fixtureExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+				names = append(names, entry.Name())
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			files = append(files, filepath.Join(dir, set, name))
+		}
+	}
+
+	return LoadFiles(files...)
+}
+
+// LoadFiles reads the fixture files in order, a customer listed again replaces the earlier entry
+func LoadFiles(files ...string) ([]CustomerFixture, error) {
+	fixtures := []CustomerFixture{}
+	for _, file := range files {
+		loaded, err := LoadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, loaded...)
+	}
+
+	return Dedupe(fixtures), nil
+}
+
+// Dedupe keeps one fixture per natural key, at the position of its first occurrence and with
+// the values of its last
+func Dedupe(fixtures []CustomerFixture) []CustomerFixture {
+	index := map[string]int{}
+	deduped := make([]CustomerFixture, 0, len(fixtures))
+
+	for _, fixture := range fixtures {
+		fixture.Name = strings.TrimSpace(fixture.Name)
+		if i, ok := index[fixture.Name]; ok {
+			deduped[i] = fixture
+			continue
+		}
+		index[fixture.Name] = len(deduped)
+		deduped = append(deduped, fixture)
+	}
+
+	return deduped
+}
+
+// Customers converts fixtures to the customers to upsert
+func Customers(fixtures []CustomerFixture) []*entity.Customer {
+	customers := make([]*entity.Customer, 0, len(fixtures))
+	for _, fixture := range fixtures {
+		customers = append(customers, &entity.Customer{Name: fixture.Name, Age: fixture.Age})
+	}
+	return customers
+}
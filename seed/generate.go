@@ -0,0 +1,81 @@
+package seed
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+const (
+	minGeneratedAge = 18
+	maxGeneratedAge = 80
+)
+
+type namePool struct {
+	first, last []string
+}
+
+var (
+	thaiNames = namePool{
+		first: []string{
+			"สมชาย", "สมศักดิ์", "สมหญิง", "วิชัย", "ประเสริฐ", "สุนทร", "อนุชา", "กิตติ", "ธนพล", "ณัฐพล",
+			"ศิริพร", "สุภาพร", "วราภรณ์", "กมลวรรณ", "นภัสสร", "ปิยะนุช", "พิมพ์ชนก", "ธิดารัตน์", "อรอุมา", "จันทร์เพ็ญ",
+		},
+		last: []string{
+			"ใจดี", "รักไทย", "แสงทอง", "ศรีสุข", "บุญมา", "วงศ์สวัสดิ์", "สุขสวัสดิ์", "ทองดี", "พรหมมา", "เจริญผล",
+			"รัตนกร", "แก้วมณี", "ศรีวงศ์", "มั่นคง", "พงษ์พันธ์", "สายสุวรรณ", "จันทร์แก้ว", "ชัยมงคล", "อินทรสุข", "ธนากร",
+		},
+	}
+	// romanized Thai names, as they appear in passports and English language systems
+	thaiRomanNames = namePool{
+		first: []string{
+			"Somchai", "Somsak", "Somying", "Wichai", "Prasert", "Sunthorn", "Anucha", "Kitti", "Thanaphon", "Nattaphon",
+			"Siriporn", "Supaporn", "Waraporn", "Kamonwan", "Napatsorn", "Piyanuch", "Pimchanok", "Thidarat", "Onuma", "Chanpen",
+		},
+		last: []string{
+			"Jaidee", "Rakthai", "Saengthong", "Srisuk", "Boonma", "Wongsawat", "Suksawat", "Thongdee", "Phromma", "Charoenphon",
+			"Rattanakorn", "Kaewmanee", "Sriwong", "Mankong", "Phongphan", "Saisuwan", "Chankaew", "Chaimongkol", "Intarasuk", "Thanakorn",
+		},
+	}
+	englishNames = namePool{
+		first: []string{
+			"James", "John", "Robert", "Michael", "William", "David", "Richard", "Thomas", "Daniel", "Matthew",
+			"Mary", "Patricia", "Jennifer", "Linda", "Elizabeth", "Susan", "Jessica", "Sarah", "Emily", "Olivia",
+		},
+		last: []string{
+			"Smith", "Johnson", "Williams", "Brown", "Jones", "Miller", "Davis", "Wilson", "Anderson", "Taylor",
+			"Thomas", "Moore", "Jackson", "Martin", "Lee", "Thompson", "White", "Harris", "Clark", "Lewis",
+		},
+	}
+)
+
+// Generate returns n synthetic customers for load testing, half with Thai names in Thai script
+// or romanized and half with English names. The same seed always yields the same customers, so
+// seeding them again updates nothing. Names are unique, a repeated name gets a number appended.
+func Generate(n int, seed int64) []CustomerFixture {
+	r := rand.New(rand.NewSource(seed))
+	used := map[string]int{}
+	fixtures := make([]CustomerFixture, 0, n)
+
+	for i := 0; i < n; i++ {
+		pool := englishNames
+		if r.Intn(2) == 0 {
+			pool = thaiNames
+			if r.Intn(2) == 0 {
+				pool = thaiRomanNames
+			}
+		}
+
+		name := pool.first[r.Intn(len(pool.first))] + " " + pool.last[r.Intn(len(pool.last))]
+		used[name]++
+		if count := used[name]; count > 1 {
+			name += " " + strconv.Itoa(count)
+		}
+
+		fixtures = append(fixtures, CustomerFixture{
+			Name: name,
+			Age:  minGeneratedAge + r.Intn(maxGeneratedAge-minGeneratedAge+1),
+		})
+	}
+
+	return fixtures
+}
@@ -0,0 +1,78 @@
+package seed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf8"
+
+	"itmx_test/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestLoadSet(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base", "01_customers.yml"), "customers:\n  - name: John Doe\n    age: 23\n")
+	writeFile(t, filepath.Join(dir, "base", "02_customers.json"), `{"customers": [{"name": "Jane Smith", "age": 44}]}`)
+	writeFile(t, filepath.Join(dir, "base", "notes.txt"), "ignored")
+	writeFile(t, filepath.Join(dir, "staging", "customers.yaml"), "customers:\n  - name: ' John Doe '\n    age: 30\n  - name: สมชาย ใจดี\n    age: 35\n")
+
+	t.Run("environment on top of base", func(t *testing.T) {
+		fixtures, err := LoadSet(dir, "staging")
+		assert.NoError(t, err)
+		assert.Equal(t, []CustomerFixture{
+			{Name: "John Doe", Age: 30},
+			{Name: "Jane Smith", Age: 44},
+			{Name: "สมชาย ใจดี", Age: 35},
+		}, fixtures)
+	})
+
+	t.Run("missing environment", func(t *testing.T) {
+		fixtures, err := LoadSet(dir, "production")
+		assert.NoError(t, err)
+		assert.Len(t, fixtures, 2)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		fixtures, err := LoadSet(filepath.Join(dir, "missing"), "staging")
+		assert.NoError(t, err)
+		assert.Empty(t, fixtures)
+	})
+
+	t.Run("invalid fixture", func(t *testing.T) {
+		writeFile(t, filepath.Join(dir, "broken", "customers.yml"), "customers:\n  - name: Old\n    age: 200\n")
+
+		_, err := LoadSet(dir, "broken")
+		assert.ErrorContains(t, err, "customer 1")
+	})
+}
+
+func TestGenerate(t *testing.T) {
+	fixtures := Generate(500, 42)
+	assert.Len(t, fixtures, 500)
+	assert.Equal(t, fixtures, Generate(500, 42))
+	assert.NotEqual(t, fixtures, Generate(500, 43))
+
+	names := map[string]bool{}
+	thai := 0
+	for _, fixture := range fixtures {
+		assert.NoError(t, validation.Validate(fixture))
+		assert.False(t, names[fixture.Name], fixture.Name)
+		names[fixture.Name] = true
+
+		if r, _ := utf8.DecodeRuneInString(fixture.Name); r >= 0x0E00 && r <= 0x0E7F {
+			thai++
+		}
+	}
+	assert.Greater(t, thai, 0)
+	assert.Len(t, Dedupe(fixtures), 500)
+
+	assert.Empty(t, Generate(0, 1))
+}
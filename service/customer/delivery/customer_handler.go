@@ -1,18 +1,27 @@
 package delivery
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
 	"itmx_test/domain"
+	"itmx_test/logging"
 	"itmx_test/middleware"
-	"itmx_test/service/entity"
+	"itmx_test/problem"
 	"itmx_test/service/customer/usecase"
+	"itmx_test/service/entity"
+	"itmx_test/validation"
 
+	"github.com/go-playground/validator"
 	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
 )
 
-type ResponseError struct {
-	Message string `json:"message"`
-}
-
 type CustomerHandler struct {
 	cu usecase.CustomerUsecase
 }
@@ -25,12 +34,31 @@ func NewCustomerHandler(f *fiber.App, cu usecase.CustomerUsecase) {
 
 	// Create
 	customer.Post("", handler.CreateCustomer)
+	customer.Post("/bulk", handler.BulkCreateCustomers)
+
+	// List
+	customer.Get("", handler.ListCustomers)
+
+	// Search
+	customer.Get("/search", handler.SearchCustomers)
+
+	// Import / Export
+	customer.Get("/export", handler.ExportCustomers)
+	customer.Post("/import", handler.ImportCustomers)
+
+	// Trash
+	customer.Get("/deleted", handler.ListDeletedCustomers)
+	customer.Post("/:id/restore", handler.RestoreCustomer)
+
+	// History
+	customer.Get("/:id/history", handler.GetCustomerHistory)
 
 	// GetByID
 	customer.Get("/:id", handler.GetCustomer)
 
 	// Update
 	customer.Put("/:id", handler.UpdateCustomer)
+	customer.Patch("/:id", handler.PatchCustomer)
 
 	// Delete By ID
 	customer.Delete("/:id", handler.DeleteCustomer)
@@ -46,12 +74,12 @@ func (ch *CustomerHandler) CreateCustomer(c *fiber.Ctx) error {
 
 	// Parser input
 	if err := c.BodyParser(&input); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(err.Error())
+		return problem.MalformedBody(err)
 	}
 
 	// Validate input
-	if err := middleware.Validate(input); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(middleware.ErrorResponse(err))
+	if err := validation.Validate(input); err != nil {
+		return err
 	}
 
 	cutomer := &entity.Customer{
@@ -60,8 +88,8 @@ func (ch *CustomerHandler) CreateCustomer(c *fiber.Ctx) error {
 	}
 
 	// create customer usecase
-	if err := ch.cu.CreateCustomer(cutomer); err != nil {
-		return c.Status(domain.GetStatusCode(err)).JSON(ResponseError{Message: err.Error()})
+	if err := ch.cu.CreateCustomer(requestContext(c), cutomer); err != nil {
+		return errorResponse(c, err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -69,20 +97,428 @@ func (ch *CustomerHandler) CreateCustomer(c *fiber.Ctx) error {
 	})
 }
 
+const maxBulkItems = 1000
+
+type BulkCreateQuery struct {
+	Mode string `query:"mode" validate:"omitempty,oneof=atomic partial"`
+}
+
+type BulkItemResult struct {
+	Index  int                    `json:"index"`
+	ID     string                 `json:"id,omitempty"`
+	Status string                 `json:"status"`
+	Errors map[string]interface{} `json:"errors,omitempty"`
+}
+
+// BulkCreateCustomers validates every item and inserts the valid ones in one transaction.
+// In atomic mode (the default) a single invalid item rejects the whole request, in partial
+// mode invalid items are skipped and reported.
+func (ch *CustomerHandler) BulkCreateCustomers(c *fiber.Ctx) error {
+	var query BulkCreateQuery
+	var input []CustomerBody
+
+	// Parser query
+	if err := c.QueryParser(&query); err != nil {
+		return problem.BadRequest(err.Error())
+	}
+
+	// Validate query
+	if err := validation.Validate(query); err != nil {
+		return err
+	}
+
+	// Parser input
+	if err := c.BodyParser(&input); err != nil {
+		return problem.MalformedBody(err)
+	}
+
+	if len(input) == 0 || len(input) > maxBulkItems {
+		return problem.BadRequest(fmt.Sprintf("bulk request must contain between 1 and %d items", maxBulkItems))
+	}
+
+	results := make([]BulkItemResult, len(input))
+	customers := make([]*entity.Customer, 0, len(input))
+	indexes := make([]int, 0, len(input))
+	invalid := 0
+	var fieldErrors []problem.FieldError
+
+	// Validate input
+	for i, item := range input {
+		results[i].Index = i
+		if err := validation.Validate(item); err != nil {
+			results[i].Status = "invalid"
+			results[i].Errors = validation.ErrorResponse(err)
+			fieldErrors = append(fieldErrors, problem.FieldErrors(err.(validator.ValidationErrors), fmt.Sprintf("[%d].", i))...)
+			invalid++
+			continue
+		}
+
+		customers = append(customers, &entity.Customer{
+			Name: item.Name,
+			Age:  item.Age,
+		})
+		indexes = append(indexes, i)
+	}
+
+	if invalid > 0 && query.Mode != "partial" {
+		for _, i := range indexes {
+			results[i].Status = "skipped"
+		}
+		invalidItems := problem.FromDomain(domain.ErrValidationFailed, fmt.Sprintf("%d of %d items failed validation", invalid, len(input)))
+		invalidItems.Errors = fieldErrors
+		return invalidItems.With("created", 0).With("failed", invalid).With("results", results)
+	}
+
+	// create customers usecase
+	if err := ch.cu.CreateCustomers(requestContext(c), customers); err != nil {
+		return errorResponse(c, err)
+	}
+
+	for n, i := range indexes {
+		results[i].Status = "created"
+		results[i].ID = customers[n].ID
+	}
+
+	status := fiber.StatusCreated
+	if invalid > 0 {
+		status = fiber.StatusMultiStatus
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"created": len(customers),
+		"failed":  invalid,
+		"results": results,
+	})
+}
+
 func (ch *CustomerHandler) GetCustomer(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	customer, err := ch.cu.GetCustomerByID(id)
+	customer, err := ch.cu.GetCustomerByID(requestContext(c), id)
 	if err != nil {
-		return c.Status(domain.GetStatusCode(err)).JSON(ResponseError{Message: err.Error()})
+		return errorResponse(c, err)
 	}
 
+	c.Set(fiber.HeaderETag, versionETag(customer.Version))
+
 	return c.Status(fiber.StatusOK).JSON(customer)
 }
 
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion reads the version the client expects from the If-Match header. Zero means
+// the header was absent or "*" and no version check is made.
+func ifMatchVersion(c *fiber.Ctx) (int, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+	if err != nil || version < 1 {
+		return 0, domain.ErrVersionMismatch
+	}
+
+	return version, nil
+}
+
+type CustomerListQuery struct {
+	Page   int    `query:"page" validate:"omitempty,min=1"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Name   string `query:"name" validate:"max=100"`
+	MinAge int    `query:"min_age" validate:"omitempty,min=1,max=110"`
+	MaxAge int    `query:"max_age" validate:"omitempty,min=1,max=110,gtefield=MinAge"`
+	Sort   string `query:"sort" validate:"omitempty,oneof=created_at name age"`
+	Order  string `query:"order" validate:"omitempty,oneof=asc desc"`
+	Mode   string `query:"mode" validate:"omitempty,oneof=offset cursor"`
+	Cursor string `query:"cursor"`
+}
+
+func (ch *CustomerHandler) ListCustomers(c *fiber.Ctx) error {
+	var input CustomerListQuery
+
+	// Parser query
+	if err := c.QueryParser(&input); err != nil {
+		return problem.BadRequest(err.Error())
+	}
+
+	// Validate query
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	filter := &entity.CustomerFilter{
+		Page:      input.Page,
+		Limit:     input.Limit,
+		Name:      input.Name,
+		MinAge:    input.MinAge,
+		MaxAge:    input.MaxAge,
+		SortBy:    input.Sort,
+		SortOrder: input.Order,
+	}
+
+	// keyset pagination always walks in id order, so sort and page are ignored
+	if input.Mode == "cursor" || input.Cursor != "" {
+		page, err := ch.cu.GetCustomersByCursor(requestContext(c), filter, input.Cursor)
+		if err != nil {
+			return errorResponse(c, err)
+		}
+
+		return c.Status(fiber.StatusOK).JSON(page)
+	}
+
+	page, err := ch.cu.GetCustomers(requestContext(c), filter)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+type CustomerTrashQuery struct {
+	Page  int    `query:"page" validate:"omitempty,min=1"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Name  string `query:"name" validate:"max=100"`
+}
+
+func (ch *CustomerHandler) ListDeletedCustomers(c *fiber.Ctx) error {
+	var input CustomerTrashQuery
+
+	// Parser query
+	if err := c.QueryParser(&input); err != nil {
+		return problem.BadRequest(err.Error())
+	}
+
+	// Validate query
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	filter := &entity.CustomerFilter{
+		Page:  input.Page,
+		Limit: input.Limit,
+		Name:  input.Name,
+	}
+
+	page, err := ch.cu.GetDeletedCustomers(requestContext(c), filter)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+func (ch *CustomerHandler) GetCustomerHistory(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	audits, err := ch.cu.GetCustomerHistory(requestContext(c), id)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": audits,
+	})
+}
+
+// requestContext is the context handed to the usecase. It carries the request deadline, the
+// audit meta of the request and a logger with the route and the customer ID.
+func requestContext(c *fiber.Ctx) context.Context {
+	return entity.ContextWithAuditMeta(logContext(c), auditMeta(c))
+}
+
+func logContext(c *fiber.Ctx) context.Context {
+	fields := logrus.Fields{"route": c.Route().Path}
+	if id := c.Params("id"); id != "" {
+		fields["customer_id"] = id
+	}
+	return logging.WithFields(c.UserContext(), fields)
+}
+
+// errorResponse hands err to the error handler, which logs it with the customer ID too
+func errorResponse(c *fiber.Ctx, err error) error {
+	c.SetUserContext(logContext(c))
+	return err
+}
+
+// auditMeta identifies who made the request for the customer audit trail. The actor is the
+// authenticated principal, a name sent by the client is not trusted.
+func auditMeta(c *fiber.Ctx) entity.AuditMeta {
+	actor := middleware.Principal(c)
+	if actor == "" {
+		actor = "anonymous"
+	}
+
+	return entity.AuditMeta{
+		Actor:     actor,
+		RequestID: middleware.RequestID(c),
+	}
+}
+
+func (ch *CustomerHandler) RestoreCustomer(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	if err := ch.cu.RestoreCustomerByID(requestContext(c), id); err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+type CustomerSearchQuery struct {
+	Q     string `query:"q" validate:"required,max=100"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (ch *CustomerHandler) SearchCustomers(c *fiber.Ctx) error {
+	var input CustomerSearchQuery
+
+	// Parser query
+	if err := c.QueryParser(&input); err != nil {
+		return problem.BadRequest(err.Error())
+	}
+
+	// Validate query
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	results, err := ch.cu.SearchCustomers(requestContext(c), input.Q, input.Limit)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": results,
+	})
+}
+
+type CustomerExportQuery struct {
+	Format string `query:"format" validate:"omitempty,oneof=csv ndjson"`
+	Name   string `query:"name" validate:"max=100"`
+	MinAge int    `query:"min_age" validate:"omitempty,min=1,max=110"`
+	MaxAge int    `query:"max_age" validate:"omitempty,min=1,max=110,gtefield=MinAge"`
+}
+
+var exportContentTypes = map[string]string{
+	usecase.FormatCSV:    "text/csv; charset=utf-8",
+	usecase.FormatNDJSON: "application/x-ndjson",
+}
+
+func (ch *CustomerHandler) ExportCustomers(c *fiber.Ctx) error {
+	var input CustomerExportQuery
+
+	// Parser query
+	if err := c.QueryParser(&input); err != nil {
+		return problem.BadRequest(err.Error())
+	}
+
+	// Validate query
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	format := input.Format
+	if format == "" {
+		format = usecase.FormatCSV
+	}
+
+	filter := &entity.CustomerFilter{
+		Name:   input.Name,
+		MinAge: input.MinAge,
+		MaxAge: input.MaxAge,
+	}
+
+	c.Set(fiber.HeaderContentType, exportContentTypes[format])
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="customers.%s"`, format))
+
+	// the body is written after the handler returns, so an error here can only be logged. The
+	// request context is cancelled once the handler returns, the export keeps its values only.
+	ctx := context.WithoutCancel(requestContext(c))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := ch.cu.ExportCustomers(ctx, w, format, filter); err != nil {
+			logging.FromContext(ctx).WithError(err).Error("export customers")
+		}
+		w.Flush()
+	})
+
+	return nil
+}
+
+type CustomerImportQuery struct {
+	Format string `query:"format" validate:"omitempty,oneof=csv ndjson"`
+	Map    string `query:"map" validate:"max=500"`
+}
+
+func (ch *CustomerHandler) ImportCustomers(c *fiber.Ctx) error {
+	var input CustomerImportQuery
+
+	// Parser query
+	if err := c.QueryParser(&input); err != nil {
+		return problem.BadRequest(err.Error())
+	}
+
+	// Validate query
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	format := input.Format
+	if format == "" {
+		format = usecase.FormatCSV
+		if strings.Contains(c.Get(fiber.HeaderContentType), "ndjson") {
+			format = usecase.FormatNDJSON
+		}
+	}
+
+	mapping, err := parseColumnMapping(input.Map)
+	if err != nil {
+		return problem.BadRequest(err.Error())
+	}
+
+	// read straight from the connection when the server streams request bodies
+	var body io.Reader = c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+
+	report, err := ch.cu.ImportCustomers(requestContext(c), body, format, mapping)
+	if err != nil {
+		if report == nil {
+			return errorResponse(c, err)
+		}
+		return errorResponse(c, problem.FromError(err).With("report", report))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// parseColumnMapping reads "source:target" pairs such as "full_name:name,years:age"
+func parseColumnMapping(raw string) (map[string]string, error) {
+	mapping := map[string]string{}
+	if raw == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		source, target, ok := strings.Cut(pair, ":")
+		source = strings.ToLower(strings.TrimSpace(source))
+		target = strings.ToLower(strings.TrimSpace(target))
+		if !ok || source == "" || (target != "name" && target != "age") {
+			return nil, fmt.Errorf("invalid column mapping %q", pair)
+		}
+		mapping[source] = target
+	}
+
+	return mapping, nil
+}
+
+// CustomerUpdateBody is a full replacement of the customer, every field is required
 type CustomerUpdateBody struct {
-	Name string `json:"name" validate:"max=100"`
-	Age  int    `json:"age" validate:"numeric,min=1,max=110"`
+	Name string `json:"name" validate:"required,max=100"`
+	Age  int    `json:"age" validate:"required,numeric,min=1,max=110"`
 }
 
 func (ch *CustomerHandler) UpdateCustomer(c *fiber.Ctx) error {
@@ -91,31 +527,111 @@ func (ch *CustomerHandler) UpdateCustomer(c *fiber.Ctx) error {
 
 	// Parser input
 	if err := c.BodyParser(&input); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(err.Error())
+		return problem.MalformedBody(err)
 	}
 
 	// Validate input
-	if err := middleware.Validate(input); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(middleware.ErrorResponse(err))
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return errorResponse(c, err)
 	}
 
 	cutomerUpdate := &entity.Customer{
-		Name: input.Name,
-		Age:  input.Age,
+		Name:    input.Name,
+		Age:     input.Age,
+		Version: version,
 	}
 
-	if err := ch.cu.UpdateCustomerByID(cutomerUpdate, id); err != nil {
-		return c.Status(domain.GetStatusCode(err)).JSON(ResponseError{Message: err.Error()})
+	if err := ch.cu.UpdateCustomerByID(requestContext(c), cutomerUpdate, id); err != nil {
+		return errorResponse(c, err)
 	}
 
+	c.Set(fiber.HeaderETag, versionETag(cutomerUpdate.Version))
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
+// PatchCustomer changes only the fields present in the request. The body is an RFC 7396 merge
+// patch, or an RFC 6902 JSON Patch when sent as application/json-patch+json.
+func (ch *CustomerHandler) PatchCustomer(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	customer, err := ch.cu.GetCustomerByID(requestContext(c), id)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	if version != 0 && version != customer.Version {
+		return errorResponse(c, domain.ErrVersionMismatch)
+	}
+
+	input := CustomerBody{
+		Name: customer.Name,
+		Age:  customer.Age,
+	}
+
+	// Apply patch
+	applyPatch := applyMergePatch
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), MIMEJSONPatch) {
+		applyPatch = applyJSONPatch
+	}
+	if err := applyPatch(&input, c.Body()); err != nil {
+		return problem.BadRequest(err.Error())
+	}
+
+	// Validate patched customer
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	// the patch was computed from this version, so the update must not land on a newer one
+	cutomerUpdate := &entity.Customer{
+		Name:    input.Name,
+		Age:     input.Age,
+		Version: customer.Version,
+	}
+
+	if err := ch.cu.UpdateCustomerByID(requestContext(c), cutomerUpdate, id); err != nil {
+		return errorResponse(c, err)
+	}
+
+	c.Set(fiber.HeaderETag, versionETag(cutomerUpdate.Version))
+
+	return c.Status(fiber.StatusOK).JSON(cutomerUpdate)
+}
+
 func (ch *CustomerHandler) DeleteCustomer(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	if err := ch.cu.DelCustomerByID(id); err != nil {
-		return c.Status(domain.GetStatusCode(err)).JSON(ResponseError{Message: err.Error()})
+	// permanent delete is reserved for admins
+	if c.QueryBool("purge") {
+		if !middleware.IsAdmin(c) {
+			return errorResponse(c, domain.ErrPermissionDenied)
+		}
+
+		if err := ch.cu.PurgeCustomerByID(requestContext(c), id); err != nil {
+			return errorResponse(c, err)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	if err := ch.cu.DelCustomerByID(requestContext(c), id, version); err != nil {
+		return errorResponse(c, err)
 	}
 
 	return c.SendStatus(fiber.StatusOK)
@@ -2,13 +2,19 @@ package delivery
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"itmx_test/domain"
+	"itmx_test/middleware"
+	"itmx_test/problem"
 	"itmx_test/service/entity"
 
 	"github.com/gofiber/fiber/v2"
@@ -18,33 +24,135 @@ import (
 
 type MockCustomerService struct {
 	mock.Mock
+	// ctx and audit are the context of the last call and the audit meta it carried
+	ctx   context.Context
+	audit entity.AuditMeta
 }
 
-func (m *MockCustomerService) CreateCustomer(customer *entity.Customer) error {
+func (m *MockCustomerService) GetCustomerHistory(ctx context.Context, id string) ([]*entity.CustomerAudit, error) {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
+	args := m.Called(id)
+	if audits, ok := args.Get(0).([]*entity.CustomerAudit); ok {
+		return audits, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCustomerService) CreateCustomer(ctx context.Context, customer *entity.Customer) error {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
 	args := m.Called(customer)
 	return args.Error(0)
 }
 
-func (m *MockCustomerService) GetCustomerByID(id string) (*entity.Customer, error) {
+func (m *MockCustomerService) CreateCustomers(ctx context.Context, customers []*entity.Customer) error {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
+	args := m.Called(customers)
+	for i, customer := range customers {
+		customer.ID = fmt.Sprintf("id-%d", i)
+	}
+	return args.Error(0)
+}
+
+func (m *MockCustomerService) GetCustomerByID(ctx context.Context, id string) (*entity.Customer, error) {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
 	args := m.Called(id)
+	if customer, ok := args.Get(0).(*entity.Customer); ok {
+		return customer, args.Error(1)
+	}
 	return &entity.Customer{}, args.Error(1)
 }
 
-func (m *MockCustomerService) UpdateCustomerByID(customer *entity.Customer, id string) error {
-	args := m.Called(customer, id)
+func (m *MockCustomerService) GetCustomers(ctx context.Context, filter *entity.CustomerFilter) (*entity.CustomerPage, error) {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
+	args := m.Called(filter)
+	if page, ok := args.Get(0).(*entity.CustomerPage); ok {
+		return page, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCustomerService) GetCustomersByCursor(ctx context.Context, filter *entity.CustomerFilter, cursor string) (*entity.CustomerCursorPage, error) {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
+	args := m.Called(filter, cursor)
+	if page, ok := args.Get(0).(*entity.CustomerCursorPage); ok {
+		return page, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCustomerService) SearchCustomers(ctx context.Context, term string, limit int) ([]*entity.CustomerSearchResult, error) {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
+	args := m.Called(term, limit)
+	if results, ok := args.Get(0).([]*entity.CustomerSearchResult); ok {
+		return results, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCustomerService) ExportCustomers(ctx context.Context, w io.Writer, format string, filter *entity.CustomerFilter) error {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
+	args := m.Called(format, filter)
+	io.WriteString(w, "id,name,age,created_at,updated_at\n")
+	return args.Error(0)
+}
+
+func (m *MockCustomerService) ImportCustomers(ctx context.Context, r io.Reader, format string, mapping map[string]string) (*entity.ImportReport, error) {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
+	body, _ := io.ReadAll(r)
+	args := m.Called(string(body), format, mapping)
+	if report, ok := args.Get(0).(*entity.ImportReport); ok {
+		return report, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCustomerService) SeedCustomers(ctx context.Context, customers []*entity.Customer) (*entity.SeedReport, error) {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
+	args := m.Called(customers)
+	if report, ok := args.Get(0).(*entity.SeedReport); ok {
+		return report, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCustomerService) GetDeletedCustomers(ctx context.Context, filter *entity.CustomerFilter) (*entity.CustomerPage, error) {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
+	args := m.Called(filter)
+	if page, ok := args.Get(0).(*entity.CustomerPage); ok {
+		return page, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCustomerService) RestoreCustomerByID(ctx context.Context, id string) error {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
+	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockCustomerService) DelCustomerByID(id string) error {
+func (m *MockCustomerService) PurgeCustomerByID(ctx context.Context, id string) error {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
 	args := m.Called(id)
 	return args.Error(0)
 }
 
+func (m *MockCustomerService) UpdateCustomerByID(ctx context.Context, customer *entity.Customer, id string) error {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
+	args := m.Called(customer, id)
+	return args.Error(0)
+}
+
+func (m *MockCustomerService) DelCustomerByID(ctx context.Context, id string, version int) error {
+	m.ctx, m.audit = ctx, entity.AuditMetaFromContext(ctx)
+	args := m.Called(id, version)
+	return args.Error(0)
+}
+
 func TestCreateCustomerHandler(t *testing.T) {
 	mockService := new(MockCustomerService)
 	handler := &CustomerHandler{cu: mockService}
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
 	NewCustomerHandler(app, mockService)
 	app.Post("/customers", handler.CreateCustomer)
 
@@ -91,7 +199,7 @@ func TestCreateCustomerHandler(t *testing.T) {
 		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 
 		// Assert the response body contains the expected error message
-		expectedResponse := `{"Age":"Age is min","Name":"Name is required"}`
+		expectedResponse := `{"type":"about:blank","title":"Bad Request","status":400,"code":"validation_failed","detail":"request failed validation","errors":[{"field":"Name","code":"required","message":"Name is required"},{"field":"Age","code":"min","message":"Age is min"}]}`
 		bodyBytes, err := ioutil.ReadAll(resp.Body)
 		assert.NoError(t, err)
 		assert.Equal(t, expectedResponse, string(bodyBytes))
@@ -101,11 +209,108 @@ func TestCreateCustomerHandler(t *testing.T) {
 	})
 }
 
+func TestBulkCreateCustomersHandler(t *testing.T) {
+	mockService := new(MockCustomerService)
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	NewCustomerHandler(app, mockService)
+
+	t.Run("successful bulk creation", func(t *testing.T) {
+		// Mock behavior for CreateCustomers
+		mockService.On("CreateCustomers", mock.Anything).Return(nil).Once()
+
+		// Make request to create customers
+		reqBody := `[{"name": "John Doe", "age": 30}, {"name": "Jane Smith", "age": 44}]`
+		req := httptest.NewRequest("POST", "/customers/bulk", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		// Assert the response body contains the generated ids
+		expectedResponse := `{"created":2,"failed":0,"results":[{"index":0,"id":"id-0","status":"created"},{"index":1,"id":"id-1","status":"created"}]}`
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedResponse, string(bodyBytes))
+
+		// Assert that the expected method was called
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("atomic mode rejects invalid items", func(t *testing.T) {
+		// Make request with one invalid customer
+		reqBody := `[{"name": "John Doe", "age": 30}, {"name": "", "age": 30}]`
+		req := httptest.NewRequest("POST", "/customers/bulk", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		// Assert the response body reports the invalid item
+		expectedResponse := `{"code":"validation_failed","created":0,"detail":"1 of 2 items failed validation","errors":[{"code":"required","field":"[1].Name","message":"Name is required"}],"failed":1,"results":[{"index":0,"status":"skipped"},{"index":1,"status":"invalid","errors":{"Name":"Name is required"}}],"status":400,"title":"Bad Request","type":"about:blank"}`
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedResponse, string(bodyBytes))
+
+		// Assert that nothing was created
+		mockService.AssertNumberOfCalls(t, "CreateCustomers", 1)
+	})
+
+	t.Run("partial mode creates valid items", func(t *testing.T) {
+		// Mock behavior for CreateCustomers
+		mockService.On("CreateCustomers", mock.MatchedBy(func(customers []*entity.Customer) bool {
+			return len(customers) == 1 && customers[0].Name == "John Doe"
+		})).Return(nil).Once()
+
+		// Make request with one invalid customer
+		reqBody := `[{"name": "", "age": 30}, {"name": "John Doe", "age": 30}]`
+		req := httptest.NewRequest("POST", "/customers/bulk?mode=partial", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusMultiStatus, resp.StatusCode)
+
+		// Assert the response body reports both items
+		expectedResponse := `{"created":1,"failed":1,"results":[{"index":0,"status":"invalid","errors":{"Name":"Name is required"}},{"index":1,"id":"id-0","status":"created"}]}`
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedResponse, string(bodyBytes))
+
+		// Assert that the expected method was called
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		// Make request with no customers
+		req := httptest.NewRequest("POST", "/customers/bulk", bytes.NewBufferString(`[]`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
+
 func TestGetCustomerHandler(t *testing.T) {
 	mockService := new(MockCustomerService)
 	handler := &CustomerHandler{cu: mockService}
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
 	NewCustomerHandler(app, mockService)
 	app.Get("/customers/:id", handler.GetCustomer)
 
@@ -133,6 +338,22 @@ func TestGetCustomerHandler(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 
+	t.Run("etag carries the version", func(t *testing.T) {
+		// Mock behavior for GetCustomerByID
+		mockService.On("GetCustomerByID", "2").Return(&entity.Customer{ID: "2", Name: "test", Age: 11, Version: 4}, nil)
+
+		// Make request to get a customer
+		req := httptest.NewRequest("GET", "/customers/2", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the ETag header is the version
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, `"4"`, resp.Header.Get("ETag"))
+	})
+
 	t.Run("error getting customer", func(t *testing.T) {
 		// Mock behavior for GetCustomerByID
 		mockService.On("GetCustomerByID", "invalid_id").Return(nil, domain.ErrNotFound)
@@ -147,35 +368,38 @@ func TestGetCustomerHandler(t *testing.T) {
 		// Assert that the HTTP status code is correct
 		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
 
-		// Assert that the response body matches the expected error message
-		var responseBody ResponseError
+		// Assert that the response body is a problem document with the expected detail
+		assert.Equal(t, problem.ContentType, resp.Header.Get(fiber.HeaderContentType))
+		var responseBody problem.Problem
 		err = json.NewDecoder(resp.Body).Decode(&responseBody)
 		assert.NoError(t, err)
-		assert.Equal(t, "your requested Item is not found", responseBody.Message)
+		assert.Equal(t, fiber.StatusNotFound, responseBody.Status)
+		assert.Equal(t, "not_found", responseBody.Code)
+		assert.Equal(t, "your requested Item is not found", responseBody.Detail)
 
 		// Assert that the expected method was called
 		mockService.AssertExpectations(t)
 	})
 }
 
-func TestUpdateCustomerHandler(t *testing.T) {
+func TestListCustomersHandler(t *testing.T) {
 	mockService := new(MockCustomerService)
-	handler := &CustomerHandler{cu: mockService}
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
 	NewCustomerHandler(app, mockService)
-	app.Put("/customers/:id", handler.UpdateCustomer)
-
-	t.Run("valid request body", func(t *testing.T) {
-		// Prepare a valid request body
-		validReqBody := `{"name": "John Doe", "age": 30}`
 
-		// Mock service response
-		mockService.On("UpdateCustomerByID", mock.AnythingOfType("*entity.Customer"), mock.AnythingOfType("string")).Return(nil)
+	t.Run("successful list customers", func(t *testing.T) {
+		// Mock behavior for GetCustomers
+		expectedFilter := &entity.CustomerFilter{Page: 2, Limit: 10, Name: "jo", MinAge: 18, SortBy: "name", SortOrder: "asc"}
+		next := 3
+		expectedPage := &entity.CustomerPage{
+			Data: []*entity.Customer{{ID: "1", Name: "John Doe", Age: 30}},
+			Meta: entity.PageMeta{Page: 2, Limit: 10, Total: 25, TotalPages: 3, NextPage: &next},
+		}
+		mockService.On("GetCustomers", expectedFilter).Return(expectedPage, nil)
 
-		// Make request with valid request body
-		req := httptest.NewRequest("PUT", "/customers/1", bytes.NewBufferString(validReqBody))
-		req.Header.Set("Content-Type", "application/json")
+		// Make request to list customers
+		req := httptest.NewRequest("GET", "/customers?page=2&limit=10&name=jo&min_age=18&sort=name&order=asc", nil)
 		resp, err := app.Test(req)
 
 		// Assert that there were no errors
@@ -184,17 +408,62 @@ func TestUpdateCustomerHandler(t *testing.T) {
 		// Assert that the HTTP status code is correct
 		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
 
+		// Assert that the response body matches the expected page
+		var responseBody entity.CustomerPage
+		err = json.NewDecoder(resp.Body).Decode(&responseBody)
+		assert.NoError(t, err)
+		assert.Len(t, responseBody.Data, 1)
+		assert.Equal(t, int64(25), responseBody.Meta.Total)
+		assert.Equal(t, 3, *responseBody.Meta.NextPage)
+
 		// Assert that the expected method was called
 		mockService.AssertExpectations(t)
 	})
 
-	t.Run("invalid request body", func(t *testing.T) {
-		// Prepare an invalid request body (age out of range)
-		invalidReqBody := `{"name": "John Doe", "age": 150}`
+	t.Run("successful list customers by cursor", func(t *testing.T) {
+		// Mock behavior for GetCustomersByCursor
+		expectedPage := &entity.CustomerCursorPage{
+			Data: []*entity.Customer{{ID: "2", Name: "Jane Smith", Age: 44}},
+			Meta: entity.CursorMeta{Limit: 1, NextCursor: "next-token", HasMore: true},
+		}
+		mockService.On("GetCustomersByCursor", &entity.CustomerFilter{Limit: 1}, "token").Return(expectedPage, nil)
 
-		// Make request with invalid request body
-		req := httptest.NewRequest("PUT", "/customers/1", bytes.NewBufferString(invalidReqBody))
-		req.Header.Set("Content-Type", "application/json")
+		// Make request with a cursor
+		req := httptest.NewRequest("GET", "/customers?limit=1&cursor=token", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		// Assert that the response body matches the expected page
+		var responseBody entity.CustomerCursorPage
+		err = json.NewDecoder(resp.Body).Decode(&responseBody)
+		assert.NoError(t, err)
+		assert.Equal(t, "next-token", responseBody.Meta.NextCursor)
+		assert.True(t, responseBody.Meta.HasMore)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		// Mock behavior for GetCustomersByCursor
+		mockService.On("GetCustomersByCursor", mock.Anything, "bad").Return(nil, domain.ErrInvalidCursor)
+
+		// Make request with a tampered cursor
+		req := httptest.NewRequest("GET", "/customers?cursor=bad", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		// Make request with an unsupported sort field
+		req := httptest.NewRequest("GET", "/customers?sort=password", nil)
 		resp, err := app.Test(req)
 
 		// Assert that there were no errors
@@ -204,67 +473,737 @@ func TestUpdateCustomerHandler(t *testing.T) {
 		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 
 		// Assert that the expected error message is returned
-		expectedErrorMessage := `{"Age":"Age is max"}`
+		expectedErrorMessage := `{"type":"about:blank","title":"Bad Request","status":400,"code":"validation_failed","detail":"request failed validation","errors":[{"field":"Sort","code":"oneof","message":"Sort is oneof"}]}`
 		bodyBytes, err := ioutil.ReadAll(resp.Body)
 		assert.NoError(t, err)
 		assert.Equal(t, expectedErrorMessage, string(bodyBytes))
+	})
+}
+
+func TestSearchCustomersHandler(t *testing.T) {
+	mockService := new(MockCustomerService)
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	NewCustomerHandler(app, mockService)
+
+	t.Run("successful search", func(t *testing.T) {
+		// Mock behavior for SearchCustomers
+		expectedResults := []*entity.CustomerSearchResult{
+			{Customer: &entity.Customer{ID: "1", Name: "John Doe", Age: 30}, Snippet: "<mark>John</mark> Doe", Rank: -1.5},
+		}
+		mockService.On("SearchCustomers", "john", 5).Return(expectedResults, nil)
+
+		// Make request to search customers
+		req := httptest.NewRequest("GET", "/customers/search?q=john&limit=5", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		// Assert that the response body matches the expected results
+		var responseBody struct {
+			Data []*entity.CustomerSearchResult `json:"data"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&responseBody)
+		assert.NoError(t, err)
+		assert.Len(t, responseBody.Data, 1)
+		assert.Equal(t, "<mark>John</mark> Doe", responseBody.Data[0].Snippet)
 
 		// Assert that the expected method was called
 		mockService.AssertExpectations(t)
 	})
 
-	// t.Run("not found user id", func(t *testing.T) {
-	// 	// Prepare a valid request body
-	// 	validReqBody := `{"name": "John Doe", "age": 30}`
+	t.Run("missing query", func(t *testing.T) {
+		// Make request without a search term
+		req := httptest.NewRequest("GET", "/customers/search", nil)
+		resp, err := app.Test(req)
 
-	// 	// Mock service response
-	// 	mockService.On("UpdateCustomerByID", mock.AnythingOfType("*entity.Customer"), mock.AnythingOfType("string")).Return(domain.ErrNotFound)
+		// Assert that there were no errors
+		assert.NoError(t, err)
 
-	// 	// Make request with valid request body
-	// 	req := httptest.NewRequest("PUT", "/customers/invalid_id", bytes.NewBufferString(validReqBody))
-	// 	req.Header.Set("Content-Type", "application/json")
-	// 	resp, err := app.Test(req)
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 
-	// 	// Assert that there were no errors
-	// 	assert.NoError(t, err)
+		// Assert that the expected error message is returned
+		expectedErrorMessage := `{"type":"about:blank","title":"Bad Request","status":400,"code":"validation_failed","detail":"request failed validation","errors":[{"field":"Q","code":"required","message":"Q is required"}]}`
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedErrorMessage, string(bodyBytes))
+	})
+}
 
-	// 	// Assert that the HTTP status code is correct
-	// 	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+func TestExportCustomersHandler(t *testing.T) {
+	mockService := new(MockCustomerService)
 
-	// 	// Assert that the response body matches the expected error message
-	// 	var responseBody ResponseError
-	// 	err = json.NewDecoder(resp.Body).Decode(&responseBody)
-	// 	assert.NoError(t, err)
-	// 	assert.Equal(t, "your requested Item is not found", responseBody.Message)
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	NewCustomerHandler(app, mockService)
 
-	// 	// Assert that the expected method was called
-	// 	mockService.AssertExpectations(t)
-	// })
+	t.Run("successful csv export", func(t *testing.T) {
+		// Mock behavior for ExportCustomers
+		mockService.On("ExportCustomers", "csv", &entity.CustomerFilter{MinAge: 18}).Return(nil)
+
+		// Make request to export customers
+		req := httptest.NewRequest("GET", "/customers/export?format=csv&min_age=18", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code and headers are correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="customers.csv"`, resp.Header.Get("Content-Disposition"))
+
+		// Assert the streamed body
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "id,name,age,created_at,updated_at\n", string(bodyBytes))
+
+		// Assert that the expected method was called
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		// Make request with an unknown format
+		req := httptest.NewRequest("GET", "/customers/export?format=xml", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
 }
 
-func TestDeleteCustomerHandler(t *testing.T) {
+func TestImportCustomersHandler(t *testing.T) {
 	mockService := new(MockCustomerService)
-	handler := &CustomerHandler{cu: mockService}
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
 	NewCustomerHandler(app, mockService)
-	app.Delete("/customers/:id", handler.DeleteCustomer)
 
-	t.Run("delete customer by existing id", func(t *testing.T) {
-		// Mock service response
-		mockService.On("DelCustomerByID", mock.AnythingOfType("string")).Return(nil)
-	
-		// Make request with valid id
-		req := httptest.NewRequest("DELETE", "/customers/existing_id", nil)
+	t.Run("successful import", func(t *testing.T) {
+		// Mock behavior for ImportCustomers
+		reqBody := "full_name,age\nJohn Doe,30\n,20\n"
+		report := &entity.ImportReport{
+			Imported: 1,
+			Failed:   1,
+			Errors:   []entity.ImportRowError{{Row: 3, Errors: map[string]interface{}{"Name": "Name is required"}}},
+		}
+		mockService.On("ImportCustomers", reqBody, "csv", map[string]string{"full_name": "name"}).Return(report, nil)
+
+		// Make request to import customers
+		req := httptest.NewRequest("POST", "/customers/import?map=full_name:name", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "text/csv")
 		resp, err := app.Test(req)
-	
+
 		// Assert that there were no errors
 		assert.NoError(t, err)
-	
+
 		// Assert that the HTTP status code is correct
 		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
-	
+
+		// Assert the response body contains the report
+		expectedResponse := `{"imported":1,"failed":1,"errors":[{"row":3,"errors":{"Name":"Name is required"}}]}`
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedResponse, string(bodyBytes))
+
 		// Assert that the expected method was called
 		mockService.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+
+	t.Run("invalid mapping", func(t *testing.T) {
+		// Make request with a mapping to an unknown field
+		req := httptest.NewRequest("POST", "/customers/import?map=email:password", bytes.NewBufferString("name,age\n"))
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("invalid header", func(t *testing.T) {
+		// Mock behavior for ImportCustomers
+		mockService.On("ImportCustomers", `{"name":"a"}`, "ndjson", map[string]string{}).Return(nil, domain.ErrInvalidImportHeader)
+
+		// Make request with ndjson content
+		req := httptest.NewRequest("POST", "/customers/import", bytes.NewBufferString(`{"name":"a"}`))
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestUpdateCustomerHandler(t *testing.T) {
+	mockService := new(MockCustomerService)
+	handler := &CustomerHandler{cu: mockService}
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	NewCustomerHandler(app, mockService)
+	app.Put("/customers/:id", handler.UpdateCustomer)
+
+	t.Run("valid request body", func(t *testing.T) {
+		// Prepare a valid request body
+		validReqBody := `{"name": "John Doe", "age": 30}`
+
+		// Mock service response
+		mockService.On("UpdateCustomerByID", mock.AnythingOfType("*entity.Customer"), mock.AnythingOfType("string")).Return(nil)
+
+		// Make request with valid request body
+		req := httptest.NewRequest("PUT", "/customers/1", bytes.NewBufferString(validReqBody))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		// Assert that the expected method was called
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid request body", func(t *testing.T) {
+		// Prepare an invalid request body (age out of range)
+		invalidReqBody := `{"name": "John Doe", "age": 150}`
+
+		// Make request with invalid request body
+		req := httptest.NewRequest("PUT", "/customers/1", bytes.NewBufferString(invalidReqBody))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		// Assert that the expected error message is returned
+		expectedErrorMessage := `{"type":"about:blank","title":"Bad Request","status":400,"code":"validation_failed","detail":"request failed validation","errors":[{"field":"Age","code":"max","message":"Age is max"}]}`
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedErrorMessage, string(bodyBytes))
+
+		// Assert that the expected method was called
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("missing field in full replacement", func(t *testing.T) {
+		// Prepare a request body without age
+		invalidReqBody := `{"name": "John Doe"}`
+
+		// Make request with partial request body
+		req := httptest.NewRequest("PUT", "/customers/1", bytes.NewBufferString(invalidReqBody))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		// Assert that the expected error message is returned
+		expectedErrorMessage := `{"type":"about:blank","title":"Bad Request","status":400,"code":"validation_failed","detail":"request failed validation","errors":[{"field":"Age","code":"required","message":"Age is required"}]}`
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedErrorMessage, string(bodyBytes))
+	})
+
+	// t.Run("not found user id", func(t *testing.T) {
+	// 	// Prepare a valid request body
+	// 	validReqBody := `{"name": "John Doe", "age": 30}`
+
+	// 	// Mock service response
+	// 	mockService.On("UpdateCustomerByID", mock.AnythingOfType("*entity.Customer"), mock.AnythingOfType("string")).Return(domain.ErrNotFound)
+
+	// 	// Make request with valid request body
+	// 	req := httptest.NewRequest("PUT", "/customers/invalid_id", bytes.NewBufferString(validReqBody))
+	// 	req.Header.Set("Content-Type", "application/json")
+	// 	resp, err := app.Test(req)
+
+	// 	// Assert that there were no errors
+	// 	assert.NoError(t, err)
+
+	// 	// Assert that the HTTP status code is correct
+	// 	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	// 	// Assert that the response body matches the expected error message
+	// 	var responseBody problem.Problem
+	// 	err = json.NewDecoder(resp.Body).Decode(&responseBody)
+	// 	assert.NoError(t, err)
+	// 	assert.Equal(t, "your requested Item is not found", responseBody.Detail)
+
+	// 	// Assert that the expected method was called
+	// 	mockService.AssertExpectations(t)
+	// })
+}
+
+func TestUpdateCustomerHandlerIfMatch(t *testing.T) {
+	mockService := new(MockCustomerService)
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	NewCustomerHandler(app, mockService)
+
+	t.Run("stale if-match", func(t *testing.T) {
+		// Mock service response
+		mockService.On("UpdateCustomerByID", &entity.Customer{Name: "John Doe", Age: 30, Version: 2}, "2").Return(domain.ErrVersionMismatch)
+
+		// Make request with an outdated version
+		req := httptest.NewRequest("PUT", "/customers/2", bytes.NewBufferString(`{"name": "John Doe", "age": 30}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"2"`)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode)
+	})
+
+	t.Run("malformed if-match", func(t *testing.T) {
+		// Make request with an etag that is not a version
+		req := httptest.NewRequest("PUT", "/customers/2", bytes.NewBufferString(`{"name": "John Doe", "age": 30}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"abc"`)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode)
+	})
+}
+
+func TestPatchCustomerHandler(t *testing.T) {
+	mockService := new(MockCustomerService)
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	NewCustomerHandler(app, mockService)
+
+	// the handler writes the patch into the customer it loaded, so each call gets a fresh copy
+	existing := func() *entity.Customer {
+		return &entity.Customer{ID: "1", Name: "John Doe", Age: 30}
+	}
+
+	t.Run("merge patch keeps omitted fields", func(t *testing.T) {
+		// Mock service response
+		mockService.On("GetCustomerByID", "1").Return(existing(), nil).Once()
+		mockService.On("UpdateCustomerByID", &entity.Customer{Name: "John Doe", Age: 31}, "1").Return(nil).Once()
+
+		// Make request with only the age
+		req := httptest.NewRequest("PATCH", "/customers/1", bytes.NewBufferString(`{"age": 31}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		// Assert that the response body is the patched customer
+		var responseBody entity.Customer
+		err = json.NewDecoder(resp.Body).Decode(&responseBody)
+		assert.NoError(t, err)
+		assert.Equal(t, "John Doe", responseBody.Name)
+		assert.Equal(t, 31, responseBody.Age)
+
+		// Assert that the expected method was called
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("merge patch null clears a required field", func(t *testing.T) {
+		// Mock service response
+		mockService.On("GetCustomerByID", "1").Return(existing(), nil).Once()
+
+		// Make request removing the name
+		req := httptest.NewRequest("PATCH", "/customers/1", bytes.NewBufferString(`{"name": null}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		// Assert that the expected error message is returned
+		expectedErrorMessage := `{"type":"about:blank","title":"Bad Request","status":400,"code":"validation_failed","detail":"request failed validation","errors":[{"field":"Name","code":"required","message":"Name is required"}]}`
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedErrorMessage, string(bodyBytes))
+	})
+
+	t.Run("merge patch with unknown member", func(t *testing.T) {
+		// Mock service response
+		mockService.On("GetCustomerByID", "1").Return(existing(), nil).Once()
+
+		// Make request with a field that does not exist
+		req := httptest.NewRequest("PATCH", "/customers/1", bytes.NewBufferString(`{"email": "a@b.c"}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("json patch", func(t *testing.T) {
+		// Mock service response
+		mockService.On("GetCustomerByID", "1").Return(existing(), nil).Once()
+		mockService.On("UpdateCustomerByID", &entity.Customer{Name: "Johnny", Age: 30}, "1").Return(nil).Once()
+
+		// Make request with a test and a replace operation
+		reqBody := `[{"op": "test", "path": "/age", "value": 30}, {"op": "replace", "path": "/name", "value": "Johnny"}]`
+		req := httptest.NewRequest("PATCH", "/customers/1", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json-patch+json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		// Assert that the expected method was called
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("json patch failing test operation", func(t *testing.T) {
+		// Mock service response
+		mockService.On("GetCustomerByID", "1").Return(existing(), nil).Once()
+
+		// Make request with a test operation that does not match
+		reqBody := `[{"op": "test", "path": "/age", "value": 99}, {"op": "replace", "path": "/name", "value": "Johnny"}]`
+		req := httptest.NewRequest("PATCH", "/customers/1", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json-patch+json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		// Assert that the expected error message is returned
+		var responseBody problem.Problem
+		err = json.NewDecoder(resp.Body).Decode(&responseBody)
+		assert.NoError(t, err)
+		assert.Equal(t, "operation 0: test failed for /age", responseBody.Detail)
+	})
+
+	t.Run("stale if-match", func(t *testing.T) {
+		// Mock service response
+		mockService.On("GetCustomerByID", "1").Return(&entity.Customer{ID: "1", Name: "John Doe", Age: 30, Version: 3}, nil).Once()
+
+		// Make request with an outdated version
+		req := httptest.NewRequest("PATCH", "/customers/1", bytes.NewBufferString(`{"age": 31}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", `W/"2"`)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		// Mock service response
+		mockService.On("GetCustomerByID", "invalid_id").Return(nil, domain.ErrNotFound)
+
+		// Make request for a missing customer
+		req := httptest.NewRequest("PATCH", "/customers/invalid_id", bytes.NewBufferString(`{"age": 31}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestDeleteCustomerHandler(t *testing.T) {
+	mockService := new(MockCustomerService)
+	handler := &CustomerHandler{cu: mockService}
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	NewCustomerHandler(app, mockService)
+	app.Delete("/customers/:id", handler.DeleteCustomer)
+
+	t.Run("delete customer by existing id", func(t *testing.T) {
+		// Mock service response
+		mockService.On("DelCustomerByID", mock.AnythingOfType("string"), 0).Return(nil)
+
+		// Make request with valid id
+		req := httptest.NewRequest("DELETE", "/customers/existing_id", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		// Assert that the expected method was called
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("delete customer with if-match", func(t *testing.T) {
+		// Mock service response
+		mockService.On("DelCustomerByID", "versioned_id", 3).Return(domain.ErrVersionMismatch)
+
+		// Make request with an expected version
+		req := httptest.NewRequest("DELETE", "/customers/versioned_id", nil)
+		req.Header.Set("If-Match", `"3"`)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode)
+	})
+}
+
+func TestCustomerTrashHandlers(t *testing.T) {
+	mockService := new(MockCustomerService)
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Use(middleware.AdminMiddleware("secret"))
+	NewCustomerHandler(app, mockService)
+
+	t.Run("list deleted customers", func(t *testing.T) {
+		// Mock behavior for GetDeletedCustomers
+		expectedPage := &entity.CustomerPage{
+			Data: []*entity.Customer{{ID: "1", Name: "John Doe", Age: 30}},
+			Meta: entity.PageMeta{Page: 1, Limit: 20, Total: 1, TotalPages: 1},
+		}
+		mockService.On("GetDeletedCustomers", &entity.CustomerFilter{Name: "john"}).Return(expectedPage, nil)
+
+		// Make request to list the trash
+		req := httptest.NewRequest("GET", "/customers/deleted?name=john", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		// Assert that the response body matches the expected page
+		var responseBody entity.CustomerPage
+		err = json.NewDecoder(resp.Body).Decode(&responseBody)
+		assert.NoError(t, err)
+		assert.Len(t, responseBody.Data, 1)
+	})
+
+	t.Run("restore customer", func(t *testing.T) {
+		// Mock behavior for RestoreCustomerByID
+		mockService.On("RestoreCustomerByID", "1").Return(nil)
+
+		// Make request to restore a customer
+		req := httptest.NewRequest("POST", "/customers/1/restore", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("restore customer that is not deleted", func(t *testing.T) {
+		// Mock behavior for RestoreCustomerByID
+		mockService.On("RestoreCustomerByID", "2").Return(domain.ErrNotFound)
+
+		// Make request to restore an active customer
+		req := httptest.NewRequest("POST", "/customers/2/restore", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("purge requires admin", func(t *testing.T) {
+		// Make request without the admin token
+		req := httptest.NewRequest("DELETE", "/customers/1?purge=true", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+		// Assert that nothing was purged
+		mockService.AssertNotCalled(t, "PurgeCustomerByID", "1")
+	})
+
+	t.Run("purge as admin", func(t *testing.T) {
+		// Mock behavior for PurgeCustomerByID
+		mockService.On("PurgeCustomerByID", "1").Return(nil)
+
+		// Make request with the admin token
+		req := httptest.NewRequest("DELETE", "/customers/1?purge=true", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		// Assert that the expected method was called
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestCustomerHistoryHandler(t *testing.T) {
+	mockService := new(MockCustomerService)
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Use(middleware.RequestIDMiddleware())
+	app.Use(middleware.AdminMiddleware("test-admin-token-0123"))
+	NewCustomerHandler(app, mockService)
+
+	t.Run("get customer history", func(t *testing.T) {
+		// Mock behavior for GetCustomerHistory
+		audits := []*entity.CustomerAudit{
+			{ID: 1, CustomerID: "1", Operation: entity.AuditCreate, Actor: "alice", Changes: map[string]entity.FieldChange{"name": {To: "John"}}},
+			{ID: 2, CustomerID: "1", Operation: entity.AuditUpdate, Actor: "bob", Changes: map[string]entity.FieldChange{"age": {From: float64(30), To: float64(31)}}},
+		}
+		mockService.On("GetCustomerHistory", "1").Return(audits, nil)
+
+		// Make request to get the history
+		req := httptest.NewRequest("GET", "/customers/1/history", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		// Assert that the response body contains the audit records
+		var responseBody struct {
+			Data []*entity.CustomerAudit `json:"data"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&responseBody)
+		assert.NoError(t, err)
+		assert.Len(t, responseBody.Data, 2)
+		assert.Equal(t, "bob", responseBody.Data[1].Actor)
+		assert.Equal(t, float64(31), responseBody.Data[1].Changes["age"].To)
+	})
+
+	t.Run("history of a customer that never existed", func(t *testing.T) {
+		// Mock behavior for GetCustomerHistory
+		mockService.On("GetCustomerHistory", "missing").Return(nil, domain.ErrNotFound)
+
+		// Make request to get the history
+		req := httptest.NewRequest("GET", "/customers/missing/history", nil)
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+		// Assert that the response body is the not found problem
+		body, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(body), `"code":"not_found"`)
+	})
+
+	t.Run("writes carry the actor and request id", func(t *testing.T) {
+		// Mock behavior for DelCustomerByID
+		mockService.On("DelCustomerByID", "1", 0).Return(nil)
+
+		// Make request with the admin token
+		req := httptest.NewRequest("DELETE", "/customers/1", nil)
+		req.Header.Set("X-Admin-Token", "test-admin-token-0123")
+		req.Header.Set("X-Request-ID", "req-1")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		// Assert that the audit metadata was passed on
+		assert.Equal(t, entity.AuditMeta{Actor: middleware.AdminPrincipal, RequestID: "req-1"}, mockService.audit)
+	})
+
+	t.Run("a claimed user is not the actor", func(t *testing.T) {
+		// Mock behavior for DelCustomerByID
+		mockService.On("DelCustomerByID", "1", 0).Return(nil)
+
+		// Make request naming a user without authenticating
+		req := httptest.NewRequest("DELETE", "/customers/1", nil)
+		req.Header.Set("X-User", "alice")
+		req.Header.Set("X-Request-ID", "req-2")
+		resp, err := app.Test(req)
+
+		// Assert that there were no errors
+		assert.NoError(t, err)
+
+		// Assert that the HTTP status code is correct
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		// Assert that the request is audited as anonymous
+		assert.Equal(t, entity.AuditMeta{Actor: "anonymous", RequestID: "req-2"}, mockService.audit)
+	})
+}
+
+func TestCustomerHandlerContext(t *testing.T) {
+	mockService := new(MockCustomerService)
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Use(middleware.TimeoutMiddleware(middleware.TimeoutConfig{Default: time.Minute}))
+	NewCustomerHandler(app, mockService)
+
+	t.Run("request deadline reaches the usecase", func(t *testing.T) {
+		var deadline bool
+		mockService.On("GetCustomerByID", "1").Return(&entity.Customer{ID: "1"}, nil).Run(func(args mock.Arguments) {
+			_, deadline = mockService.ctx.Deadline()
+		}).Once()
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/customers/1", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.True(t, deadline)
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		mockService.On("GetCustomerByID", "2").Return(nil, context.DeadlineExceeded).Once()
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/customers/2", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusGatewayTimeout, resp.StatusCode)
+	})
+}
@@ -0,0 +1,128 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+const (
+	MIMEMergePatch = "application/merge-patch+json"
+	MIMEJSONPatch  = "application/json-patch+json"
+)
+
+var errPatchNotObject = errors.New("merge patch must be a JSON object")
+
+// applyMergePatch applies an RFC 7396 merge patch to the customer document. Only the members
+// present in the patch are changed, a null member clears the field.
+func applyMergePatch(target *CustomerBody, raw []byte) error {
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &patch); err != nil || patch == nil {
+		return errPatchNotObject
+	}
+
+	for member, value := range patch {
+		if err := setCustomerField(target, "/"+member, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch applies an RFC 6902 JSON Patch to the customer document. Operations are
+// applied to a copy so a failing operation leaves the target untouched.
+func applyJSONPatch(target *CustomerBody, raw []byte) error {
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(raw, &operations); err != nil {
+		return errors.New("json patch must be an array of operations")
+	}
+
+	doc := *target
+	for i, operation := range operations {
+		if err := applyJSONPatchOperation(&doc, operation); err != nil {
+			return fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	*target = doc
+	return nil
+}
+
+func applyJSONPatchOperation(doc *CustomerBody, operation jsonPatchOperation) error {
+	switch operation.Op {
+	case "add", "replace":
+		if operation.Value == nil {
+			return errors.New("value is required")
+		}
+		return setCustomerField(doc, operation.Path, operation.Value)
+	case "remove":
+		return setCustomerField(doc, operation.Path, json.RawMessage("null"))
+	case "test":
+		current, err := getCustomerField(doc, operation.Path)
+		if err != nil {
+			return err
+		}
+		var expected interface{}
+		if err := json.Unmarshal(operation.Value, &expected); err != nil {
+			return errors.New("value is not valid JSON")
+		}
+		if !reflect.DeepEqual(current, expected) {
+			return fmt.Errorf("test failed for %s", operation.Path)
+		}
+		return nil
+	case "copy", "move":
+		value, err := getCustomerField(doc, operation.From)
+		if err != nil {
+			return err
+		}
+		raw, _ := json.Marshal(value)
+		if err := setCustomerField(doc, operation.Path, raw); err != nil {
+			return err
+		}
+		if operation.Op == "move" && operation.From != operation.Path {
+			return setCustomerField(doc, operation.From, json.RawMessage("null"))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported op %q", operation.Op)
+	}
+}
+
+func setCustomerField(doc *CustomerBody, path string, value json.RawMessage) error {
+	switch path {
+	case "/name":
+		doc.Name = ""
+		if string(value) != "null" && json.Unmarshal(value, &doc.Name) != nil {
+			return errors.New("name must be a string")
+		}
+	case "/age":
+		doc.Age = 0
+		if string(value) != "null" && json.Unmarshal(value, &doc.Age) != nil {
+			return errors.New("age must be an integer")
+		}
+	default:
+		return fmt.Errorf("unknown path %q", path)
+	}
+	return nil
+}
+
+func getCustomerField(doc *CustomerBody, path string) (interface{}, error) {
+	switch path {
+	case "/name":
+		return doc.Name, nil
+	case "/age":
+		// JSON numbers decode to float64, keep the same type so test comparisons match
+		return float64(doc.Age), nil
+	default:
+		return nil, fmt.Errorf("unknown path %q", path)
+	}
+}
@@ -1,24 +1,46 @@
 package repository
 
 import (
-	"itmx_test/service/entity"
+	"context"
+	"html"
+	"strings"
 
 	"itmx_test/domain"
+	"itmx_test/service/entity"
+
 	"gorm.io/gorm"
 )
 
 type CustomerRepository interface {
 	// Create
-	Create(customer *entity.Customer) error
+	Create(ctx context.Context, customer *entity.Customer) error
+	CreateBatch(ctx context.Context, customers []*entity.Customer) error
 
 	// Read
-	FindByID(id string) (*entity.Customer, error)
+	FindByID(ctx context.Context, id string) (*entity.Customer, error)
+	FindByNames(ctx context.Context, names []string) ([]*entity.Customer, error)
+	FindAll(ctx context.Context, filter *entity.CustomerFilter) ([]*entity.Customer, int64, error)
+	FindAfter(ctx context.Context, filter *entity.CustomerFilter, afterID string) ([]*entity.Customer, error)
+	FindDeleted(ctx context.Context, filter *entity.CustomerFilter) ([]*entity.Customer, int64, error)
+	Exists(ctx context.Context, id string) (bool, error)
+	Search(ctx context.Context, term string, limit int) ([]*entity.CustomerSearchResult, error)
 
 	// Update
-	Update(customer *entity.Customer) error
+	Update(ctx context.Context, customer *entity.Customer) error
 
 	// Delete
-	DeleteByID(id string) error
+	DeleteByID(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, id string) error
+
+	// Audit
+	CreateAudits(ctx context.Context, audits []*entity.CustomerAudit) error
+	FindAuditsByCustomerID(ctx context.Context, id string) ([]*entity.CustomerAudit, error)
+
+	// Outbox
+	CreateOutboxEvents(ctx context.Context, events []*entity.OutboxEvent) error
+
+	Transaction(ctx context.Context, fn func(repo CustomerRepository) error) error
 }
 
 type customerRepo struct {
@@ -29,60 +51,380 @@ func NewCustomerRepository(db *gorm.DB) CustomerRepository {
 	return &customerRepo{db}
 }
 
-func (cr *customerRepo) Create(customer *entity.Customer) error {
-	tx := cr.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
+// Transaction runs fn with a repository bound to a single database transaction. Writes made
+// through it commit together, or roll back together when fn returns an error. Every write
+// method uses db.Transaction, so inside fn they become savepoints instead of new transactions.
+func (cr *customerRepo) Transaction(ctx context.Context, fn func(repo CustomerRepository) error) error {
+	err := cr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&customerRepo{tx})
+	})
+	return translateError(ctx, err)
+}
+
+func (cr *customerRepo) Create(ctx context.Context, customer *entity.Customer) error {
+	err := cr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Create user
+		return tx.Create(customer).Error
+	})
+	return translateError(ctx, err)
+}
+
+func (cr *customerRepo) CreateBatch(ctx context.Context, customers []*entity.Customer) error {
+	err := cr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Create users
+		return tx.CreateInBatches(customers, 100).Error
+	})
+	return translateError(ctx, err)
+}
+
+func (cr *customerRepo) FindByID(ctx context.Context, id string) (*entity.Customer, error) {
+	customer := &entity.Customer{}
+	if err := cr.db.WithContext(ctx).Order("created_at desc").Where("id = ?", id).First(&customer).Error; err != nil {
+		return nil, translateError(ctx, err)
+	}
+	return customer, nil
+}
+
+// FindByNames returns the customers with any of the given names, oldest first
+func (cr *customerRepo) FindByNames(ctx context.Context, names []string) ([]*entity.Customer, error) {
+	customers := []*entity.Customer{}
+	if len(names) == 0 {
+		return customers, nil
 	}
 
-	// Create user
-	if err := tx.Create(customer).Error; err != nil {
-		tx.Rollback()
-		return err
+	if err := cr.db.WithContext(ctx).Where("name IN ?", names).Order("created_at asc, id asc").Find(&customers).Error; err != nil {
+		return nil, translateError(ctx, err)
 	}
+	return customers, nil
+}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		return err
+func (cr *customerRepo) FindAll(ctx context.Context, filter *entity.CustomerFilter) ([]*entity.Customer, int64, error) {
+	var total int64
+	if err := cr.db.WithContext(ctx).Model(&entity.Customer{}).Scopes(customerFilterScope(filter)).Count(&total).Error; err != nil {
+		return nil, 0, translateError(ctx, err)
 	}
 
-	return nil
+	customers := []*entity.Customer{}
+	if err := cr.db.WithContext(ctx).Scopes(customerFilterScope(filter)).
+		Order(customerOrder(filter)).
+		Limit(filter.Limit).
+		Offset((filter.Page - 1) * filter.Limit).
+		Find(&customers).Error; err != nil {
+		return nil, 0, translateError(ctx, err)
+	}
+
+	return customers, total, nil
 }
 
-func (cr *customerRepo) FindByID(id string) (*entity.Customer, error) {
-	customer := &entity.Customer{}
-	if err := cr.db.Order("created_at desc").Where("id = ?", id).First(&customer).Error; err != nil {
-		return nil, domain.ErrNotFound
+// FindDeleted lists soft deleted customers, most recently deleted first
+func (cr *customerRepo) FindDeleted(ctx context.Context, filter *entity.CustomerFilter) ([]*entity.Customer, int64, error) {
+	var total int64
+	if err := cr.db.WithContext(ctx).Unscoped().Model(&entity.Customer{}).Scopes(customerFilterScope(filter)).Where("deleted_at IS NOT NULL").Count(&total).Error; err != nil {
+		return nil, 0, translateError(ctx, err)
 	}
-	return customer, nil
+
+	customers := []*entity.Customer{}
+	if err := cr.db.WithContext(ctx).Unscoped().Scopes(customerFilterScope(filter)).
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at desc, id desc").
+		Limit(filter.Limit).
+		Offset((filter.Page - 1) * filter.Limit).
+		Find(&customers).Error; err != nil {
+		return nil, 0, translateError(ctx, err)
+	}
+
+	return customers, total, nil
+}
+
+// Exists reports whether a customer with the id is stored, soft deleted or not
+func (cr *customerRepo) Exists(ctx context.Context, id string) (bool, error) {
+	var count int64
+	if err := cr.db.WithContext(ctx).Unscoped().Model(&entity.Customer{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return false, translateError(ctx, err)
+	}
+	return count > 0, nil
+}
+
+// FindAfter walks customers in id order starting after afterID. Ids are prefixed with their
+// creation time, so this is a keyset scan that never needs an OFFSET.
+func (cr *customerRepo) FindAfter(ctx context.Context, filter *entity.CustomerFilter, afterID string) ([]*entity.Customer, error) {
+	query := cr.db.WithContext(ctx).Scopes(customerFilterScope(filter))
+	if afterID != "" {
+		query = query.Where("id > ?", afterID)
+	}
+
+	customers := []*entity.Customer{}
+	if err := query.Order("id asc").Limit(filter.Limit).Find(&customers).Error; err != nil {
+		return nil, translateError(ctx, err)
+	}
+
+	return customers, nil
+}
+
+type customerSearchRow struct {
+	ID      string
+	Snippet string
+	Rank    float64
+}
+
+// Search runs a ranked prefix search against the customer_search FTS5 index
+func (cr *customerRepo) Search(ctx context.Context, term string, limit int) ([]*entity.CustomerSearchResult, error) {
+	match := buildMatchQuery(term)
+	if match == "" {
+		return []*entity.CustomerSearchResult{}, nil
+	}
+
+	// the FTS5 index only exists on SQLite
+	if cr.db.Dialector.Name() != "sqlite" {
+		return cr.searchByLike(ctx, term, limit)
+	}
+
+	rows := []customerSearchRow{}
+	if err := cr.db.WithContext(ctx).Raw(`SELECT customers.id AS id,
+			snippet(customer_search, 1, ?, ?, '...', 16) AS snippet,
+			bm25(customer_search) AS rank
+		FROM customer_search
+		JOIN customers ON customers.id = customer_search.customer_id
+		WHERE customer_search MATCH ? AND customers.deleted_at IS NULL
+		ORDER BY rank
+		LIMIT ?`, snippetOpen, snippetClose, match, limit).Scan(&rows).Error; err != nil {
+		return nil, translateError(ctx, err)
+	}
+
+	if len(rows) == 0 {
+		return []*entity.CustomerSearchResult{}, nil
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+
+	customers := []*entity.Customer{}
+	if err := cr.db.WithContext(ctx).Where("id IN ?", ids).Find(&customers).Error; err != nil {
+		return nil, translateError(ctx, err)
+	}
+
+	byID := make(map[string]*entity.Customer, len(customers))
+	for _, customer := range customers {
+		byID[customer.ID] = customer
+	}
+
+	results := make([]*entity.CustomerSearchResult, 0, len(rows))
+	for _, row := range rows {
+		customer, ok := byID[row.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, &entity.CustomerSearchResult{
+			Customer: customer,
+			Snippet:  highlight(row.Snippet, customer.Name),
+			Rank:     row.Rank,
+		})
+	}
+
+	return results, nil
 }
 
-func (cr *customerRepo) Update(customer *entity.Customer) error {
-	tx := cr.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
+// searchByLike is the unranked search used where there is no full text index. Every word must
+// appear in the name.
+func (cr *customerRepo) searchByLike(ctx context.Context, term string, limit int) ([]*entity.CustomerSearchResult, error) {
+	query := cr.db.WithContext(ctx)
+	for _, word := range strings.Fields(term) {
+		query = query.Where("LOWER(name) LIKE ? "+likeEscape(query), containsPattern(strings.ToLower(word)))
 	}
 
-	// update
-	if err := tx.Save(customer).Error; err != nil {
-		tx.Rollback()
-		return err
+	customers := []*entity.Customer{}
+	if err := query.Order("name asc, id asc").Limit(limit).Find(&customers).Error; err != nil {
+		return nil, translateError(ctx, err)
 	}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		return err
+	results := make([]*entity.CustomerSearchResult, 0, len(customers))
+	for _, customer := range customers {
+		results = append(results, &entity.CustomerSearchResult{
+			Customer: customer,
+			Snippet:  html.EscapeString(customer.Name),
+		})
 	}
 
+	return results, nil
+}
+
+const (
+	// snippetOpen and snippetClose delimit the matches in an FTS5 snippet, they become <mark>
+	// tags once the text around them is HTML escaped
+	snippetOpen  = "\x02"
+	snippetClose = "\x03"
+)
+
+var snippetMarks = strings.NewReplacer(snippetOpen, "<mark>", snippetClose, "</mark>")
+
+// highlight turns an FTS5 snippet into HTML, the customer data escaped and the matches in <mark>
+// tags. A name holding the delimiters itself is returned escaped, without highlighting.
+func highlight(snippet, name string) string {
+	if strings.ContainsAny(name, snippetOpen+snippetClose) {
+		return html.EscapeString(name)
+	}
+	return snippetMarks.Replace(html.EscapeString(snippet))
+}
+
+// buildMatchQuery turns free text into an FTS5 query where every word is a quoted prefix
+// term, so user input can never inject FTS operators
+func buildMatchQuery(term string) string {
+	words := strings.Fields(term)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"*`
+	}
+	return strings.Join(words, " ")
+}
+
+// customerSortColumns whitelists the columns a list may be sorted by
+var customerSortColumns = map[string]string{
+	"created_at": "created_at",
+	"name":       "name",
+	"age":        "age",
+}
+
+func customerFilterScope(filter *entity.CustomerFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.Name != "" {
+			db = db.Where("name LIKE ? "+likeEscape(db), containsPattern(filter.Name))
+		}
+		if filter.MinAge > 0 {
+			db = db.Where("age >= ?", filter.MinAge)
+		}
+		if filter.MaxAge > 0 {
+			db = db.Where("age <= ?", filter.MaxAge)
+		}
+		return db
+	}
+}
+
+// likeEscaper escapes the LIKE wildcards and the escape character itself with a backslash
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern is a LIKE pattern matching values that contain s literally, so a search for
+// "_" or "%" does not match every customer
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// likeEscape is the ESCAPE clause naming the backslash, MySQL string literals escape it again
+func likeEscape(db *gorm.DB) string {
+	if db.Dialector.Name() == "mysql" {
+		return `ESCAPE '\\'`
+	}
+	return `ESCAPE '\'`
+}
+
+func customerOrder(filter *entity.CustomerFilter) string {
+	column, ok := customerSortColumns[filter.SortBy]
+	if !ok {
+		column = "created_at"
+	}
+
+	direction := "desc"
+	if filter.SortOrder == "asc" {
+		direction = "asc"
+	}
+
+	// id is a tie breaker so rows with equal sort values keep a stable order between pages
+	return column + " " + direction + ", id " + direction
+}
+
+// Update writes the customer only if the stored version still matches customer.Version,
+// then bumps the version. A stale version returns domain.ErrVersionMismatch.
+func (cr *customerRepo) Update(ctx context.Context, customer *entity.Customer) error {
+	err := cr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// update
+		result := tx.Model(customer).Where("version = ?", customer.Version).Updates(map[string]interface{}{
+			"name":    customer.Name,
+			"age":     customer.Age,
+			"version": gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			return translateError(ctx, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrVersionMismatch
+		}
+		return nil
+	})
+	if err != nil {
+		return translateError(ctx, err)
+	}
+
+	customer.Version++
+
 	return nil
 }
 
-func (cr *customerRepo) DeleteByID(id string) error {
+// DeleteByID soft deletes the customer. A non zero version makes the delete conditional on
+// the stored version.
+func (cr *customerRepo) DeleteByID(ctx context.Context, id string, version int) error {
 	customer := &entity.Customer{}
-	if err := cr.db.Where("id = ?", id).Delete(&customer).Error; err != nil {
-		return err
+	query := cr.db.WithContext(ctx).Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	result := query.Delete(&customer)
+	if result.Error != nil {
+		return translateError(ctx, result.Error)
+	}
+	if version != 0 && result.RowsAffected == 0 {
+		return domain.ErrVersionMismatch
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// Restore clears deleted_at on a soft deleted customer and bumps its version
+func (cr *customerRepo) Restore(ctx context.Context, id string) error {
+	result := cr.db.WithContext(ctx).Unscoped().Model(&entity.Customer{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return translateError(ctx, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// Purge permanently deletes the customer row
+func (cr *customerRepo) Purge(ctx context.Context, id string) error {
+	result := cr.db.WithContext(ctx).Unscoped().Where("id = ?", id).Delete(&entity.Customer{})
+	if result.Error != nil {
+		return translateError(ctx, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (cr *customerRepo) CreateAudits(ctx context.Context, audits []*entity.CustomerAudit) error {
+	if len(audits) == 0 {
+		return nil
+	}
+	return translateError(ctx, cr.db.WithContext(ctx).CreateInBatches(audits, 100).Error)
+}
+
+func (cr *customerRepo) FindAuditsByCustomerID(ctx context.Context, id string) ([]*entity.CustomerAudit, error) {
+	audits := []*entity.CustomerAudit{}
+	if err := cr.db.WithContext(ctx).Where("customer_id = ?", id).Order("id asc").Find(&audits).Error; err != nil {
+		return nil, translateError(ctx, err)
+	}
+	return audits, nil
+}
+
+func (cr *customerRepo) CreateOutboxEvents(ctx context.Context, events []*entity.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return translateError(ctx, cr.db.WithContext(ctx).CreateInBatches(events, 100).Error)
+}
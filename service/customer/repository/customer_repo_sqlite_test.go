@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"itmx_test/config"
+	"itmx_test/domain"
+	"itmx_test/service/entity"
+	"itmx_test/util"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newSQLiteRepo runs the repository against a migrated in-memory SQLite database
+func newSQLiteRepo(t *testing.T) CustomerRepository {
+	db, err := config.InitDB(config.DatabaseConfig{Driver: config.DriverMemory, AutoMigrate: true})
+	if err != nil {
+		t.Fatalf("Failed to open sqlite database: %v", err)
+	}
+
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return NewCustomerRepository(db)
+}
+
+func TestCustomerRepositorySQLite(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create, update and find", func(t *testing.T) {
+		repo := newSQLiteRepo(t)
+
+		customer := &entity.Customer{ID: util.GenerateUuid(), Name: "John Doe", Age: 23}
+		assert.NoError(t, repo.Create(ctx, customer))
+
+		found, err := repo.FindByID(ctx, customer.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, found.Version)
+
+		found.Age = 24
+		assert.NoError(t, repo.Update(ctx, found))
+		assert.Equal(t, 2, found.Version)
+
+		// the stale copy is rejected
+		customer.Age = 25
+		assert.Equal(t, domain.ErrVersionMismatch, repo.Update(ctx, customer))
+
+		_, err = repo.FindByID(ctx, "missing")
+		assert.Equal(t, domain.ErrNotFound, err)
+	})
+
+	t.Run("find by names", func(t *testing.T) {
+		repo := newSQLiteRepo(t)
+
+		assert.NoError(t, repo.CreateBatch(ctx, []*entity.Customer{
+			{ID: "1", Name: "John Doe", Age: 23},
+			{ID: "2", Name: "Jane Smith", Age: 44},
+			{ID: "3", Name: "Bob", Age: 30},
+		}))
+		assert.NoError(t, repo.DeleteByID(ctx, "3", 0))
+
+		customers, err := repo.FindByNames(ctx, []string{"Jane Smith", "Bob", "Nobody"})
+		assert.NoError(t, err)
+		assert.Len(t, customers, 1)
+		assert.Equal(t, "2", customers[0].ID)
+
+		customers, err = repo.FindByNames(ctx, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, customers)
+	})
+
+	t.Run("list and search", func(t *testing.T) {
+		repo := newSQLiteRepo(t)
+
+		assert.NoError(t, repo.CreateBatch(ctx, []*entity.Customer{
+			{ID: "1", Name: "John Doe", Age: 23},
+			{ID: "2", Name: "Jane Smith", Age: 44},
+			{ID: "3", Name: "Johnny Walker", Age: 60},
+		}))
+
+		customers, total, err := repo.FindAll(ctx, &entity.CustomerFilter{Page: 1, Limit: 10, MinAge: 30, SortBy: "age", SortOrder: "asc"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		assert.Equal(t, "2", customers[0].ID)
+
+		after, err := repo.FindAfter(ctx, &entity.CustomerFilter{Limit: 10}, "1")
+		assert.NoError(t, err)
+		assert.Len(t, after, 2)
+
+		results, err := repo.Search(ctx, "joh", 10)
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Contains(t, results[0].Snippet, "<mark>")
+	})
+
+	t.Run("customers created behind an issued cursor are walked", func(t *testing.T) {
+		repo := newSQLiteRepo(t)
+
+		first := &entity.Customer{ID: util.GenerateUuid(), Name: "Alice", Age: 30}
+		assert.NoError(t, repo.Create(ctx, first))
+
+		page, err := repo.FindAfter(ctx, &entity.CustomerFilter{Limit: 1}, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{first.ID}, customerIDs(page))
+
+		// created within the same second as the cursor's customer
+		created := []string{}
+		for i := 0; i < 20; i++ {
+			customer := &entity.Customer{ID: util.GenerateUuid(), Name: "B", Age: 30}
+			assert.NoError(t, repo.Create(ctx, customer))
+			created = append(created, customer.ID)
+		}
+
+		rest, err := repo.FindAfter(ctx, &entity.CustomerFilter{Limit: 100}, page[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, created, customerIDs(rest))
+	})
+
+	t.Run("exists", func(t *testing.T) {
+		repo := newSQLiteRepo(t)
+
+		assert.NoError(t, repo.Create(ctx, &entity.Customer{ID: "1", Name: "John Doe", Age: 23, Version: 1}))
+		assert.NoError(t, repo.DeleteByID(ctx, "1", 1))
+
+		exists, err := repo.Exists(ctx, "1")
+		assert.NoError(t, err)
+		assert.True(t, exists)
+
+		exists, err = repo.Exists(ctx, "2")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("search snippet escapes the name", func(t *testing.T) {
+		repo := newSQLiteRepo(t)
+
+		assert.NoError(t, repo.Create(ctx, &entity.Customer{ID: "1", Name: `<img src=x onerror=alert(1)> John`, Age: 23}))
+
+		results, err := repo.Search(ctx, "john", 10)
+		assert.NoError(t, err)
+		if assert.Len(t, results, 1) {
+			assert.Equal(t, `&lt;img src=x onerror=alert(1)&gt; <mark>John</mark>`, results[0].Snippet)
+		}
+	})
+
+	t.Run("name filter matches wildcards literally", func(t *testing.T) {
+		repo := newSQLiteRepo(t)
+
+		assert.NoError(t, repo.CreateBatch(ctx, []*entity.Customer{
+			{ID: "1", Name: "John Doe", Age: 23},
+			{ID: "2", Name: "john_doe", Age: 44},
+			{ID: "3", Name: "100% Jane", Age: 60},
+			{ID: "4", Name: `back\slash`, Age: 30},
+		}))
+
+		for name, id := range map[string]string{"_": "2", "%": "3", `\`: "4"} {
+			customers, total, err := repo.FindAll(ctx, &entity.CustomerFilter{Page: 1, Limit: 10, Name: name})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), total, name)
+			if assert.Len(t, customers, 1, name) {
+				assert.Equal(t, id, customers[0].ID, name)
+			}
+		}
+	})
+
+	t.Run("delete, restore and purge", func(t *testing.T) {
+		repo := newSQLiteRepo(t)
+
+		assert.NoError(t, repo.Create(ctx, &entity.Customer{ID: "1", Name: "John Doe", Age: 23}))
+
+		assert.NoError(t, repo.DeleteByID(ctx, "1", 0))
+		_, err := repo.FindByID(ctx, "1")
+		assert.Equal(t, domain.ErrNotFound, err)
+
+		results, err := repo.Search(ctx, "john", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, results)
+
+		deleted, total, err := repo.FindDeleted(ctx, &entity.CustomerFilter{Page: 1, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, "1", deleted[0].ID)
+
+		assert.NoError(t, repo.Restore(ctx, "1"))
+		assert.Equal(t, domain.ErrNotFound, repo.Restore(ctx, "1"))
+
+		assert.NoError(t, repo.Purge(ctx, "1"))
+		assert.Equal(t, domain.ErrNotFound, repo.Purge(ctx, "1"))
+	})
+
+	t.Run("transaction rolls back", func(t *testing.T) {
+		repo := newSQLiteRepo(t)
+
+		err := repo.Transaction(ctx, func(repo CustomerRepository) error {
+			if err := repo.Create(ctx, &entity.Customer{ID: "1", Name: "John Doe", Age: 23}); err != nil {
+				return err
+			}
+			if err := repo.CreateAudits(ctx, []*entity.CustomerAudit{{CustomerID: "1", Operation: entity.AuditCreate}}); err != nil {
+				return err
+			}
+			return domain.ErrConflict
+		})
+		assert.Equal(t, domain.ErrConflict, err)
+
+		_, err = repo.FindByID(ctx, "1")
+		assert.Equal(t, domain.ErrNotFound, err)
+
+		audits, err := repo.FindAuditsByCustomerID(ctx, "1")
+		assert.NoError(t, err)
+		assert.Empty(t, audits)
+	})
+	t.Run("duplicate id is a conflict on the field", func(t *testing.T) {
+		repo := newSQLiteRepo(t)
+
+		assert.NoError(t, repo.Create(ctx, &entity.Customer{ID: "1", Name: "John Doe", Age: 23}))
+		err := repo.Create(ctx, &entity.Customer{ID: "1", Name: "Jane Smith", Age: 44})
+
+		var fieldErr *domain.FieldError
+		assert.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "id", fieldErr.Field)
+		assert.ErrorIs(t, err, domain.ErrConflict)
+		assert.Equal(t, http.StatusConflict, domain.GetStatusCode(err))
+	})
+
+	t.Run("locked database is retryable", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "itmx.sqlite")
+		open := func() *gorm.DB {
+			// no busy timeout, a locked database fails at once
+			db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+			assert.NoError(t, err)
+			sqlDB, _ := db.DB()
+			t.Cleanup(func() { sqlDB.Close() })
+			return db
+		}
+
+		db := open()
+		assert.NoError(t, db.AutoMigrate(entity.Customer{}))
+
+		// another writer holds the lock
+		tx := open().Begin()
+		assert.NoError(t, tx.Create(&entity.Customer{ID: "1", Name: "John Doe", Age: 23}).Error)
+		defer tx.Rollback()
+
+		err := NewCustomerRepository(db).Create(ctx, &entity.Customer{ID: "2", Name: "Jane Smith", Age: 44})
+		assert.ErrorIs(t, err, domain.ErrDatabaseBusy)
+		assert.Equal(t, http.StatusServiceUnavailable, domain.GetStatusCode(err))
+	})
+}
+
+func customerIDs(customers []*entity.Customer) []string {
+	ids := []string{}
+	for _, customer := range customers {
+		ids = append(ids, customer.ID)
+	}
+	return ids
+}
@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"itmx_test/domain"
 	"itmx_test/service/entity"
@@ -39,7 +41,7 @@ func TestCreate(t *testing.T) {
 		mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
-		err := repo.Create(&entity.Customer{ID: util.GenerateUuid(), Name: "test", Age: 11})
+		err := repo.Create(context.Background(), &entity.Customer{ID: util.GenerateUuid(), Name: "test", Age: 11})
 		assert.NoError(t, err)
 
 		// Ensure all expectations were met
@@ -53,7 +55,58 @@ func TestCreate(t *testing.T) {
 		mock.ExpectExec("INSERT INTO").WillReturnError(gorm.ErrInvalidData)
 		mock.ExpectRollback()
 
-		err := repo.Create(&entity.Customer{ID: util.GenerateUuid(), Name: "test", Age: 11})
+		err := repo.Create(context.Background(), &entity.Customer{ID: util.GenerateUuid(), Name: "test", Age: 11})
+		assert.Error(t, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCreateBatch(t *testing.T) {
+	// Mock database
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer sqlDB.Close()
+
+	// Expectation for the sqlite version check
+	mock.ExpectQuery("select sqlite_version()").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.31.1"))
+
+	dialector := sqlite.Dialector{Conn: sqlDB}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm database: %v", err)
+	}
+
+	repo := NewCustomerRepository(gormDB)
+
+	// Success case
+	t.Run("success", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(2, 2))
+		mock.ExpectCommit()
+
+		err := repo.CreateBatch(context.Background(), []*entity.Customer{
+			{ID: util.GenerateUuid(), Name: "test", Age: 11},
+			{ID: util.GenerateUuid(), Name: "test2", Age: 12},
+		})
+		assert.NoError(t, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Failure case
+	t.Run("failure", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO").WillReturnError(gorm.ErrInvalidData)
+		mock.ExpectRollback()
+
+		err := repo.CreateBatch(context.Background(), []*entity.Customer{{ID: util.GenerateUuid(), Name: "test", Age: 11}})
 		assert.Error(t, err)
 
 		// Ensure all expectations were met
@@ -83,9 +136,10 @@ func TestFindByID(t *testing.T) {
 	// Success case
 	t.Run("success", func(t *testing.T) {
 		// Setup expectations
-		mock.ExpectQuery("SELECT").WithArgs("test-id").WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "name", "age"}).AddRow("test-id", "2024-04-25 22:17:32.000", "2024-04-25 22:17:32.000", "test_name", 30))
+		createdAt := time.Date(2024, 4, 25, 22, 17, 32, 0, time.UTC)
+		mock.ExpectQuery("SELECT").WithArgs("test-id").WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "name", "age"}).AddRow("test-id", createdAt, createdAt, "test_name", 30))
 
-		customer, err := repo.FindByID("test-id")
+		customer, err := repo.FindByID(context.Background(), "test-id")
 		assert.NoError(t, err)
 		assert.NotNil(t, customer)
 		assert.Equal(t, "test-id", customer.ID)
@@ -101,12 +155,189 @@ func TestFindByID(t *testing.T) {
 		// Setup expectations
 		mock.ExpectQuery("SELECT").WithArgs("non-existent-id").WillReturnError(gorm.ErrRecordNotFound)
 
-		_, err := repo.FindByID("non-existent-id")
+		_, err := repo.FindByID(context.Background(), "non-existent-id")
 		assert.Equal(t, domain.ErrNotFound, err)
 
 		// Ensure all expectations were met
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	// Cancelled case is not reported as not found
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := repo.FindByID(ctx, "test-id")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestFindAll(t *testing.T) {
+	// Mock database
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer sqlDB.Close()
+
+	// Expectation for the sqlite version check
+	mock.ExpectQuery("select sqlite_version()").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.31.1"))
+
+	dialector := sqlite.Dialector{Conn: sqlDB}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm database: %v", err)
+	}
+
+	repo := NewCustomerRepository(gormDB)
+
+	// Success case
+	t.Run("success", func(t *testing.T) {
+		// Setup expectations
+		createdAt := time.Date(2024, 4, 25, 22, 17, 32, 0, time.UTC)
+		mock.ExpectQuery("SELECT count\\(\\*\\) FROM `customers` WHERE name LIKE \\? ESCAPE '\\\\' AND age >= \\?").
+			WithArgs("%jo%", 18).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery("SELECT \\* FROM `customers` WHERE name LIKE \\? ESCAPE '\\\\' AND age >= \\?.*ORDER BY name asc, id asc LIMIT 2 OFFSET 2").
+			WithArgs("%jo%", 18).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "name", "age"}).AddRow("test-id", createdAt, createdAt, "john", 30))
+
+		customers, total, err := repo.FindAll(context.Background(), &entity.CustomerFilter{Page: 2, Limit: 2, Name: "jo", MinAge: 18, SortBy: "name", SortOrder: "asc"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), total)
+		assert.Len(t, customers, 1)
+		assert.Equal(t, "john", customers[0].Name)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Failure case
+	t.Run("failure", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectQuery("SELECT count").WillReturnError(gorm.ErrInvalidDB)
+
+		_, _, err := repo.FindAll(context.Background(), &entity.CustomerFilter{Page: 1, Limit: 20})
+		assert.Error(t, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestFindAfter(t *testing.T) {
+	// Mock database
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer sqlDB.Close()
+
+	// Expectation for the sqlite version check
+	mock.ExpectQuery("select sqlite_version()").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.31.1"))
+
+	dialector := sqlite.Dialector{Conn: sqlDB}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm database: %v", err)
+	}
+
+	repo := NewCustomerRepository(gormDB)
+
+	// Success case
+	t.Run("success", func(t *testing.T) {
+		// Setup expectations
+		createdAt := time.Date(2024, 4, 25, 22, 17, 32, 0, time.UTC)
+		mock.ExpectQuery("SELECT \\* FROM `customers` WHERE id > \\?.*ORDER BY id asc LIMIT 3").
+			WithArgs("20240425221732-a").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "name", "age"}).AddRow("20240425221732-b", createdAt, createdAt, "john", 30))
+
+		customers, err := repo.FindAfter(context.Background(), &entity.CustomerFilter{Limit: 3}, "20240425221732-a")
+		assert.NoError(t, err)
+		assert.Len(t, customers, 1)
+		assert.Equal(t, "20240425221732-b", customers[0].ID)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Failure case
+	t.Run("failure", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectQuery("SELECT").WillReturnError(gorm.ErrInvalidDB)
+
+		_, err := repo.FindAfter(context.Background(), &entity.CustomerFilter{Limit: 3}, "")
+		assert.Error(t, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSearch(t *testing.T) {
+	// Mock database
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer sqlDB.Close()
+
+	// Expectation for the sqlite version check
+	mock.ExpectQuery("select sqlite_version()").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.31.1"))
+
+	dialector := sqlite.Dialector{Conn: sqlDB}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm database: %v", err)
+	}
+
+	repo := NewCustomerRepository(gormDB)
+
+	// Success case
+	t.Run("success", func(t *testing.T) {
+		// Setup expectations
+		createdAt := time.Date(2024, 4, 25, 22, 17, 32, 0, time.UTC)
+		mock.ExpectQuery("FROM customer_search.*MATCH").
+			WithArgs(snippetOpen, snippetClose, `"jo"* "do""e"*`, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "snippet", "rank"}).AddRow("test-id", snippetOpen+"John"+snippetClose+" Doe", -1.2))
+		mock.ExpectQuery("SELECT \\* FROM `customers` WHERE id IN").
+			WithArgs("test-id").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "name", "age"}).AddRow("test-id", createdAt, createdAt, "John Doe", 30))
+
+		results, err := repo.Search(context.Background(), `jo do"e`, 10)
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, "John Doe", results[0].Customer.Name)
+		assert.Equal(t, "<mark>John</mark> Doe", results[0].Snippet)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Blank term case
+	t.Run("blank term", func(t *testing.T) {
+		results, err := repo.Search(context.Background(), "   ", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, results)
+
+		// Ensure no query was made
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Failure case
+	t.Run("failure", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectQuery("FROM customer_search").WillReturnError(gorm.ErrInvalidDB)
+
+		_, err := repo.Search(context.Background(), "jo", 10)
+		assert.Error(t, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestUpdate(t *testing.T) {
@@ -135,7 +366,7 @@ func TestUpdate(t *testing.T) {
 		mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
-		err := repo.Update(&entity.Customer{ID: util.GenerateUuid(), Name: "test", Age: 11})
+		err := repo.Update(context.Background(), &entity.Customer{ID: util.GenerateUuid(), Name: "test", Age: 11})
 		assert.NoError(t, err)
 
 		// Ensure all expectations were met
@@ -149,12 +380,28 @@ func TestUpdate(t *testing.T) {
 		mock.ExpectExec("UPDATE").WillReturnError(gorm.ErrInvalidData)
 		mock.ExpectRollback()
 
-		err := repo.Update(&entity.Customer{ID: util.GenerateUuid(), Name: "test", Age: 11})
+		err := repo.Update(context.Background(), &entity.Customer{ID: util.GenerateUuid(), Name: "test", Age: 11})
 		assert.Error(t, err)
 
 		// Ensure all expectations were met
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	// Stale version case
+	t.Run("stale version", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `customers` SET .*`version`=version \\+ 1.* WHERE version = \\? AND .*`id` = \\?").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 2, "test-id").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.Update(context.Background(), &entity.Customer{ID: "test-id", Name: "test", Age: 11, Version: 2})
+		assert.Equal(t, domain.ErrVersionMismatch, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestDeleteByID(t *testing.T) {
@@ -183,7 +430,7 @@ func TestDeleteByID(t *testing.T) {
 		mock.ExpectExec("UPDATE `customers` SET `deleted_at`.*").WithArgs(sqlmock.AnyArg(), "test-id").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
-		err := repo.DeleteByID("test-id")
+		err := repo.DeleteByID(context.Background(), "test-id", 0)
 		assert.NoError(t, err)
 
 		// Ensure all expectations were met
@@ -197,10 +444,264 @@ func TestDeleteByID(t *testing.T) {
 		mock.ExpectExec("UPDATE `customers` SET `deleted_at`.*").WithArgs(sqlmock.AnyArg(), "test-id").WillReturnError(gorm.ErrInvalidData)
 		mock.ExpectRollback()
 
-		err := repo.DeleteByID("test-id")
+		err := repo.DeleteByID(context.Background(), "test-id", 0)
+		assert.Error(t, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Stale version case
+	t.Run("stale version", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `customers` SET `deleted_at`.* AND version = \\?").WithArgs(sqlmock.AnyArg(), "test-id", 3).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.DeleteByID(context.Background(), "test-id", 3)
+		assert.Equal(t, domain.ErrVersionMismatch, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestFindDeleted(t *testing.T) {
+	// Mock database
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer sqlDB.Close()
+
+	// Expectation for the sqlite version check
+	mock.ExpectQuery("select sqlite_version()").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.31.1"))
+
+	dialector := sqlite.Dialector{Conn: sqlDB}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm database: %v", err)
+	}
+
+	repo := NewCustomerRepository(gormDB)
+
+	// Success case
+	t.Run("success", func(t *testing.T) {
+		// Setup expectations
+		createdAt := time.Date(2024, 4, 25, 22, 17, 32, 0, time.UTC)
+		mock.ExpectQuery("SELECT count\\(\\*\\) FROM `customers` WHERE deleted_at IS NOT NULL$").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery("SELECT \\* FROM `customers` WHERE deleted_at IS NOT NULL ORDER BY deleted_at desc, id desc LIMIT 20").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "deleted_at", "name", "age"}).AddRow("test-id", createdAt, createdAt, createdAt, "john", 30))
+
+		customers, total, err := repo.FindDeleted(context.Background(), &entity.CustomerFilter{Page: 1, Limit: 20})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Len(t, customers, 1)
+		assert.True(t, customers[0].DeletedAt.Valid)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRestore(t *testing.T) {
+	// Mock database
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer sqlDB.Close()
+
+	// Expectation for the sqlite version check
+	mock.ExpectQuery("select sqlite_version()").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.31.1"))
+
+	dialector := sqlite.Dialector{Conn: sqlDB}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm database: %v", err)
+	}
+
+	repo := NewCustomerRepository(gormDB)
+
+	// Success case
+	t.Run("success", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `customers` SET `deleted_at`=\\?,`version`=version \\+ 1.* WHERE id = \\? AND deleted_at IS NOT NULL").
+			WithArgs(nil, sqlmock.AnyArg(), "test-id").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := repo.Restore(context.Background(), "test-id")
+		assert.NoError(t, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Not deleted case
+	t.Run("not found", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `customers`").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.Restore(context.Background(), "active-id")
+		assert.Equal(t, domain.ErrNotFound, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPurge(t *testing.T) {
+	// Mock database
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer sqlDB.Close()
+
+	// Expectation for the sqlite version check
+	mock.ExpectQuery("select sqlite_version()").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.31.1"))
+
+	dialector := sqlite.Dialector{Conn: sqlDB}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm database: %v", err)
+	}
+
+	repo := NewCustomerRepository(gormDB)
+
+	// Success case
+	t.Run("success", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `customers` WHERE id = \\?").WithArgs("test-id").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := repo.Purge(context.Background(), "test-id")
+		assert.NoError(t, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Not found case
+	t.Run("not found", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `customers`").WithArgs("missing-id").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.Purge(context.Background(), "missing-id")
+		assert.Equal(t, domain.ErrNotFound, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTransaction(t *testing.T) {
+	// Mock database
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer sqlDB.Close()
+
+	// Expectation for the sqlite version check
+	mock.ExpectQuery("select sqlite_version()").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.31.1"))
+
+	dialector := sqlite.Dialector{Conn: sqlDB}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm database: %v", err)
+	}
+
+	repo := NewCustomerRepository(gormDB)
+
+	// Success case
+	t.Run("success", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO `customers`").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO `customer_audits`").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := repo.Transaction(context.Background(), func(repo CustomerRepository) error {
+			customer := &entity.Customer{ID: "test-id", Name: "test", Age: 11}
+			if err := repo.Create(context.Background(), customer); err != nil {
+				return err
+			}
+			return repo.CreateAudits(context.Background(), []*entity.CustomerAudit{{CustomerID: customer.ID, Operation: entity.AuditCreate}})
+		})
+		assert.NoError(t, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Failure case
+	t.Run("failure rolls back every write", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO `customers`").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO `customer_audits`").WillReturnError(gorm.ErrInvalidData)
+		mock.ExpectRollback()
+
+		err := repo.Transaction(context.Background(), func(repo CustomerRepository) error {
+			customer := &entity.Customer{ID: "test-id", Name: "test", Age: 11}
+			if err := repo.Create(context.Background(), customer); err != nil {
+				return err
+			}
+			return repo.CreateAudits(context.Background(), []*entity.CustomerAudit{{CustomerID: customer.ID, Operation: entity.AuditCreate}})
+		})
 		assert.Error(t, err)
 
 		// Ensure all expectations were met
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestFindAuditsByCustomerID(t *testing.T) {
+	// Mock database
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer sqlDB.Close()
+
+	// Expectation for the sqlite version check
+	mock.ExpectQuery("select sqlite_version()").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.31.1"))
+
+	dialector := sqlite.Dialector{Conn: sqlDB}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm database: %v", err)
+	}
+
+	repo := NewCustomerRepository(gormDB)
+
+	// Success case
+	t.Run("success", func(t *testing.T) {
+		// Setup expectations
+		createdAt := time.Date(2024, 4, 25, 22, 17, 32, 0, time.UTC)
+		mock.ExpectQuery("SELECT \\* FROM `customer_audits` WHERE customer_id = \\? ORDER BY id asc").
+			WithArgs("test-id").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "customer_id", "operation", "actor", "request_id", "changes", "created_at"}).
+				AddRow(1, "test-id", "update", "alice", "req-1", `{"age":{"from":11,"to":12}}`, createdAt))
+
+		audits, err := repo.FindAuditsByCustomerID(context.Background(), "test-id")
+		assert.NoError(t, err)
+		assert.Len(t, audits, 1)
+		assert.Equal(t, "alice", audits[0].Actor)
+		assert.Equal(t, float64(12), audits[0].Changes["age"].To)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
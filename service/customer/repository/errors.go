@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"itmx_test/domain"
+
+	gosqlite "github.com/glebarez/go-sqlite"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// constraint is the kind of database failure translateError tells apart
+type constraint int
+
+const (
+	otherFailure constraint = iota
+	uniqueViolation
+	foreignKeyViolation
+	busy
+)
+
+var (
+	// "UNIQUE constraint failed: customers.id"
+	sqliteColumns = regexp.MustCompile(`constraint failed: (\w+\.\w+)`)
+	// "Duplicate entry '1' for key 'customers.PRIMARY'"
+	mysqlKey = regexp.MustCompile(`for key '([^']+)'`)
+	// "... FOREIGN KEY (`customer_id`) REFERENCES ..."
+	mysqlForeignKey = regexp.MustCompile("FOREIGN KEY \\(`([^`]+)`\\)")
+	// "Key (id)=(1) already exists."
+	postgresKey = regexp.MustCompile(`Key \(([^)]+)\)`)
+)
+
+// translateError turns an error of the database into a domain error the caller can act on:
+// a missing row is domain.ErrNotFound, a unique or foreign key violation is domain.ErrConflict
+// on the offending field, a locked database is domain.ErrDatabaseBusy and anything else is
+// domain.ErrInternalServerError. The driver error stays wrapped for the logs.
+func translateError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// domain errors returned inside a transaction, or already translated
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+
+	// a cancelled or timed out query is not a database failure, whatever the driver reports
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(err, ctxErr) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrNotFound
+	}
+
+	switch kind, field := classify(err); kind {
+	case uniqueViolation, foreignKeyViolation:
+		return &domain.FieldError{Err: domain.ErrConflict, Field: field, Cause: err}
+	case busy:
+		return fmt.Errorf("%w: %w", domain.ErrDatabaseBusy, err)
+	}
+	return fmt.Errorf("%w: %w", domain.ErrInternalServerError, err)
+}
+
+// classify reads the kind of failure and the offending column from the error of the driver
+func classify(err error) (constraint, string) {
+	var sqliteErr *gosqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return uniqueViolation, column(sqliteColumns, sqliteErr.Error())
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			// SQLite does not name the column
+			return foreignKeyViolation, ""
+		}
+		// the primary result code is the low byte of the extended one
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return busy, ""
+		}
+		return otherFailure, ""
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1062: // ER_DUP_ENTRY
+			key := column(mysqlKey, mysqlErr.Message)
+			if key == "PRIMARY" {
+				key = "id"
+			}
+			return uniqueViolation, key
+		case 1451, 1452: // ER_ROW_IS_REFERENCED_2, ER_NO_REFERENCED_ROW_2
+			return foreignKeyViolation, column(mysqlForeignKey, mysqlErr.Message)
+		case 1205, 1213: // ER_LOCK_WAIT_TIMEOUT, ER_LOCK_DEADLOCK
+			return busy, ""
+		}
+		return otherFailure, ""
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			return uniqueViolation, firstNonEmpty(pgErr.ColumnName, column(postgresKey, pgErr.Detail))
+		case "23503": // foreign_key_violation
+			return foreignKeyViolation, firstNonEmpty(pgErr.ColumnName, column(postgresKey, pgErr.Detail))
+		case "40001", "40P01", "55P03": // serialization_failure, deadlock_detected, lock_not_available
+			return busy, ""
+		}
+	}
+
+	return otherFailure, ""
+}
+
+// column is the first group pattern matches in msg, without the table name
+func column(pattern *regexp.Regexp, msg string) string {
+	match := pattern.FindStringSubmatch(msg)
+	if match == nil {
+		return ""
+	}
+	name := match[1]
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"itmx_test/domain"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTranslateError(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("nil and domain errors pass through", func(t *testing.T) {
+		assert.NoError(t, translateError(ctx, nil))
+		assert.Equal(t, domain.ErrVersionMismatch, translateError(ctx, domain.ErrVersionMismatch))
+	})
+
+	t.Run("missing row", func(t *testing.T) {
+		assert.Equal(t, domain.ErrNotFound, translateError(ctx, gorm.ErrRecordNotFound))
+	})
+
+	t.Run("cancelled query", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := translateError(cancelled, errors.New("interrupted"))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		err := translateError(ctx, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'customers.PRIMARY'"})
+		var fieldErr *domain.FieldError
+		assert.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "id", fieldErr.Field)
+		assert.ErrorIs(t, err, domain.ErrConflict)
+
+		err = translateError(ctx, &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails (`itmx`.`customer_audits`, CONSTRAINT `fk_audit` FOREIGN KEY (`customer_id`) REFERENCES `customers` (`id`))"})
+		assert.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "customer_id", fieldErr.Field)
+
+		err = translateError(ctx, &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"})
+		assert.ErrorIs(t, err, domain.ErrDatabaseBusy)
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		err := translateError(ctx, &pgconn.PgError{Code: "23505", Detail: "Key (id)=(1) already exists."})
+		var fieldErr *domain.FieldError
+		assert.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "id", fieldErr.Field)
+
+		err = translateError(ctx, &pgconn.PgError{Code: "55P03"})
+		assert.ErrorIs(t, err, domain.ErrDatabaseBusy)
+	})
+
+	t.Run("anything else is internal with its cause", func(t *testing.T) {
+		cause := errors.New("disk I/O error")
+		err := translateError(ctx, cause)
+		assert.ErrorIs(t, err, domain.ErrInternalServerError)
+		assert.ErrorIs(t, err, cause)
+		assert.Contains(t, err.Error(), "disk I/O error")
+	})
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"itmx_test/domain"
+	"itmx_test/service/customer/repository"
+	"itmx_test/service/entity"
+)
+
+// GetCustomerHistory lists the audit records of the customer, which outlive a delete or a purge.
+// A customer that never existed is domain.ErrNotFound, one stored before the audit trail has an
+// empty history.
+func (cu *customerUsecase) GetCustomerHistory(ctx context.Context, id string) ([]*entity.CustomerAudit, error) {
+	audits, err := cu.customerRepo.FindAuditsByCustomerID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(audits) == 0 {
+		exists, err := cu.customerRepo.Exists(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, domain.ErrNotFound
+		}
+	}
+
+	return audits, nil
+}
+
+// customerChange describes one change made to a customer. customer is the state after the
+// change and is nil when it is not known, as for a restore or a purge.
+type customerChange struct {
+	customerID string
+	operation  string
+	changes    map[string]entity.FieldChange
+	customer   *entity.Customer
+}
+
+var operationEvents = map[string]string{
+	entity.AuditCreate:  entity.EventCustomerCreated,
+	entity.AuditUpdate:  entity.EventCustomerUpdated,
+	entity.AuditRestore: entity.EventCustomerUpdated,
+	entity.AuditDelete:  entity.EventCustomerDeleted,
+	entity.AuditPurge:   entity.EventCustomerDeleted,
+}
+
+type customerEventData struct {
+	CustomerID string                        `json:"customer_id"`
+	Operation  string                        `json:"operation"`
+	Actor      string                        `json:"actor"`
+	RequestID  string                        `json:"request_id,omitempty"`
+	Changes    map[string]entity.FieldChange `json:"changes"`
+	Customer   *customerEventCustomer        `json:"customer"`
+}
+
+type customerEventCustomer struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Age       int       `json:"age"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type customerEvent struct {
+	Type       string            `json:"type"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Data       customerEventData `json:"data"`
+}
+
+// recordChanges writes the audit record and the webhook outbox event for every change, stamped
+// with the audit meta carried by ctx. It must be called with the repository of the transaction
+// that made the changes.
+func (cu *customerUsecase) recordChanges(ctx context.Context, repo repository.CustomerRepository, changes ...customerChange) error {
+	meta := entity.AuditMetaFromContext(ctx)
+	audits := make([]*entity.CustomerAudit, 0, len(changes))
+	events := make([]*entity.OutboxEvent, 0, len(changes))
+	now := time.Now()
+
+	for _, change := range changes {
+		audits = append(audits, &entity.CustomerAudit{
+			CustomerID: change.customerID,
+			Operation:  change.operation,
+			Actor:      meta.Actor,
+			RequestID:  meta.RequestID,
+			Changes:    change.changes,
+		})
+
+		event := customerEvent{
+			Type:       operationEvents[change.operation],
+			OccurredAt: now,
+			Data: customerEventData{
+				CustomerID: change.customerID,
+				Operation:  change.operation,
+				Actor:      meta.Actor,
+				RequestID:  meta.RequestID,
+				Changes:    change.changes,
+			},
+		}
+		if change.customer != nil {
+			event.Data.Customer = &customerEventCustomer{
+				ID:        change.customer.ID,
+				Name:      change.customer.Name,
+				Age:       change.customer.Age,
+				Version:   change.customer.Version,
+				CreatedAt: change.customer.CreatedAt,
+				UpdatedAt: change.customer.UpdatedAt,
+			}
+		}
+
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		events = append(events, &entity.OutboxEvent{
+			EventType:  event.Type,
+			CustomerID: change.customerID,
+			Payload:    string(payload),
+		})
+	}
+
+	if err := repo.CreateAudits(ctx, audits); err != nil {
+		return err
+	}
+
+	return repo.CreateOutboxEvents(ctx, events)
+}
+
+// customerChanges lists the audited fields that differ between before and after. A nil
+// before records every field as newly set.
+func customerChanges(before, after *entity.Customer) map[string]entity.FieldChange {
+	changes := map[string]entity.FieldChange{}
+
+	if before == nil {
+		changes["name"] = entity.FieldChange{To: after.Name}
+		changes["age"] = entity.FieldChange{To: after.Age}
+		return changes
+	}
+
+	if before.Name != after.Name {
+		changes["name"] = entity.FieldChange{From: before.Name, To: after.Name}
+	}
+	if before.Age != after.Age {
+		changes["age"] = entity.FieldChange{From: before.Age, To: after.Age}
+	}
+
+	return changes
+}
+
+var (
+	deletedChange  = map[string]entity.FieldChange{"deleted": {From: false, To: true}}
+	restoredChange = map[string]entity.FieldChange{"deleted": {From: true, To: false}}
+)
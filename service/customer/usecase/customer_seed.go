@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+
+	"itmx_test/metrics"
+	"itmx_test/service/customer/repository"
+	"itmx_test/service/entity"
+	"itmx_test/util"
+)
+
+// SeedCustomers upserts customers using the name as their natural key, so seeding the same
+// fixtures again creates nothing. A customer whose name exists with another age is updated,
+// when a name is stored more than once the oldest customer is the one kept in sync.
+func (cu *customerUsecase) SeedCustomers(ctx context.Context, customers []*entity.Customer) (*entity.SeedReport, error) {
+	report := &entity.SeedReport{}
+
+	for start := 0; start < len(customers); start += transferBatchSize {
+		end := start + transferBatchSize
+		if end > len(customers) {
+			end = len(customers)
+		}
+
+		before := *report
+		if err := cu.customerRepo.Transaction(ctx, func(repo repository.CustomerRepository) error {
+			return cu.seedBatch(ctx, repo, customers[start:end], report)
+		}); err != nil {
+			return report, err
+		}
+
+		metrics.CountCustomerChanges(entity.AuditCreate, report.Created-before.Created)
+		metrics.CountCustomerChanges(entity.AuditUpdate, report.Updated-before.Updated)
+	}
+
+	return report, nil
+}
+
+func (cu *customerUsecase) seedBatch(ctx context.Context, repo repository.CustomerRepository, customers []*entity.Customer, report *entity.SeedReport) error {
+	names := make([]string, 0, len(customers))
+	for _, customer := range customers {
+		names = append(names, customer.Name)
+	}
+
+	existing, err := repo.FindByNames(ctx, names)
+	if err != nil {
+		return err
+	}
+
+	byName := map[string]*entity.Customer{}
+	for _, customer := range existing {
+		if _, ok := byName[customer.Name]; !ok {
+			byName[customer.Name] = customer
+		}
+	}
+
+	created := map[string]*entity.Customer{}
+	pending := []*entity.Customer{}
+
+	for _, customer := range customers {
+		// a name repeated in the fixtures overrides the earlier one
+		if customerNew, ok := created[customer.Name]; ok {
+			customerNew.Age = customer.Age
+			continue
+		}
+
+		customerExist, ok := byName[customer.Name]
+		if !ok {
+			customer.ID = util.GenerateUuid()
+			created[customer.Name] = customer
+			pending = append(pending, customer)
+			continue
+		}
+
+		if customerExist.Age == customer.Age {
+			report.Unchanged++
+			continue
+		}
+
+		before := *customerExist
+		customerExist.Age = customer.Age
+		if err := repo.Update(ctx, customerExist); err != nil {
+			return err
+		}
+		if err := cu.recordChanges(ctx, repo, customerChange{
+			customerID: customerExist.ID,
+			operation:  entity.AuditUpdate,
+			changes:    customerChanges(&before, customerExist),
+			customer:   customerExist,
+		}); err != nil {
+			return err
+		}
+		report.Updated++
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+	if err := cu.createBatch(ctx, repo, pending); err != nil {
+		return err
+	}
+	report.Created += len(pending)
+
+	return nil
+}
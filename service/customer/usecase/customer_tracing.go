@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"io"
+
+	"itmx_test/service/entity"
+	"itmx_test/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracedCustomerUsecase records a span around every call of the wrapped usecase. The
+// repository queries made by a call become children of its span.
+type tracedCustomerUsecase struct {
+	next CustomerUsecase
+}
+
+func NewTracedCustomerUsecase(next CustomerUsecase) CustomerUsecase {
+	return &tracedCustomerUsecase{next: next}
+}
+
+func customerID(id string) attribute.KeyValue {
+	return attribute.String("customer.id", id)
+}
+
+func (tu *tracedCustomerUsecase) CreateCustomer(ctx context.Context, customer *entity.Customer) error {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.CreateCustomer")
+	err := tu.next.CreateCustomer(ctx, customer)
+	span.SetAttributes(customerID(customer.ID))
+	tracing.End(span, err)
+	return err
+}
+
+func (tu *tracedCustomerUsecase) CreateCustomers(ctx context.Context, customers []*entity.Customer) error {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.CreateCustomers", attribute.Int("customer.count", len(customers)))
+	err := tu.next.CreateCustomers(ctx, customers)
+	tracing.End(span, err)
+	return err
+}
+
+func (tu *tracedCustomerUsecase) GetCustomerByID(ctx context.Context, id string) (*entity.Customer, error) {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.GetCustomerByID", customerID(id))
+	customer, err := tu.next.GetCustomerByID(ctx, id)
+	tracing.End(span, err)
+	return customer, err
+}
+
+func (tu *tracedCustomerUsecase) GetCustomers(ctx context.Context, filter *entity.CustomerFilter) (*entity.CustomerPage, error) {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.GetCustomers")
+	page, err := tu.next.GetCustomers(ctx, filter)
+	tracing.End(span, err)
+	return page, err
+}
+
+func (tu *tracedCustomerUsecase) GetDeletedCustomers(ctx context.Context, filter *entity.CustomerFilter) (*entity.CustomerPage, error) {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.GetDeletedCustomers")
+	page, err := tu.next.GetDeletedCustomers(ctx, filter)
+	tracing.End(span, err)
+	return page, err
+}
+
+func (tu *tracedCustomerUsecase) GetCustomersByCursor(ctx context.Context, filter *entity.CustomerFilter, cursor string) (*entity.CustomerCursorPage, error) {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.GetCustomersByCursor")
+	page, err := tu.next.GetCustomersByCursor(ctx, filter, cursor)
+	tracing.End(span, err)
+	return page, err
+}
+
+func (tu *tracedCustomerUsecase) SearchCustomers(ctx context.Context, term string, limit int) ([]*entity.CustomerSearchResult, error) {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.SearchCustomers")
+	results, err := tu.next.SearchCustomers(ctx, term, limit)
+	tracing.End(span, err)
+	return results, err
+}
+
+func (tu *tracedCustomerUsecase) ExportCustomers(ctx context.Context, w io.Writer, format string, filter *entity.CustomerFilter) error {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.ExportCustomers", attribute.String("export.format", format))
+	err := tu.next.ExportCustomers(ctx, w, format, filter)
+	tracing.End(span, err)
+	return err
+}
+
+func (tu *tracedCustomerUsecase) ImportCustomers(ctx context.Context, r io.Reader, format string, mapping map[string]string) (*entity.ImportReport, error) {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.ImportCustomers", attribute.String("import.format", format))
+	report, err := tu.next.ImportCustomers(ctx, r, format, mapping)
+	if report != nil {
+		span.SetAttributes(attribute.Int("import.imported", report.Imported), attribute.Int("import.failed", report.Failed))
+	}
+	tracing.End(span, err)
+	return report, err
+}
+
+func (tu *tracedCustomerUsecase) SeedCustomers(ctx context.Context, customers []*entity.Customer) (*entity.SeedReport, error) {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.SeedCustomers", attribute.Int("customer.count", len(customers)))
+	report, err := tu.next.SeedCustomers(ctx, customers)
+	tracing.End(span, err)
+	return report, err
+}
+
+func (tu *tracedCustomerUsecase) UpdateCustomerByID(ctx context.Context, customer *entity.Customer, id string) error {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.UpdateCustomerByID", customerID(id))
+	err := tu.next.UpdateCustomerByID(ctx, customer, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (tu *tracedCustomerUsecase) DelCustomerByID(ctx context.Context, id string, version int) error {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.DelCustomerByID", customerID(id))
+	err := tu.next.DelCustomerByID(ctx, id, version)
+	tracing.End(span, err)
+	return err
+}
+
+func (tu *tracedCustomerUsecase) RestoreCustomerByID(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.RestoreCustomerByID", customerID(id))
+	err := tu.next.RestoreCustomerByID(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (tu *tracedCustomerUsecase) PurgeCustomerByID(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.PurgeCustomerByID", customerID(id))
+	err := tu.next.PurgeCustomerByID(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (tu *tracedCustomerUsecase) GetCustomerHistory(ctx context.Context, id string) ([]*entity.CustomerAudit, error) {
+	ctx, span := tracing.Start(ctx, "CustomerUsecase.GetCustomerHistory", customerID(id))
+	audits, err := tu.next.GetCustomerHistory(ctx, id)
+	tracing.End(span, err)
+	return audits, err
+}
@@ -0,0 +1,294 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"itmx_test/domain"
+	"itmx_test/metrics"
+	"itmx_test/service/customer/repository"
+	"itmx_test/service/entity"
+	"itmx_test/util"
+	"itmx_test/validation"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	transferBatchSize = 500
+	maxImportErrors   = 1000
+	maxNDJSONLineSize = 1024 * 1024
+)
+
+var csvExportHeader = []string{"id", "name", "age", "created_at", "updated_at"}
+
+type customerRecord struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Age       int       `json:"age"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type customerImportRow struct {
+	Name string `validate:"required,max=100"`
+	Age  int    `validate:"required,numeric,min=1,max=110"`
+}
+
+// ExportCustomers streams every customer matching the filter to w. Rows are read in fixed size
+// keyset batches, so memory usage does not grow with the size of the table.
+func (cu *customerUsecase) ExportCustomers(ctx context.Context, w io.Writer, format string, filter *entity.CustomerFilter) error {
+	var write func(record *customerRecord) error
+	var flush func() error
+
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvExportHeader); err != nil {
+			return err
+		}
+		write = func(record *customerRecord) error {
+			return cw.Write([]string{
+				record.ID,
+				record.Name,
+				strconv.Itoa(record.Age),
+				record.CreatedAt.Format(time.RFC3339),
+				record.UpdatedAt.Format(time.RFC3339),
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case FormatNDJSON:
+		encoder := json.NewEncoder(w)
+		write = func(record *customerRecord) error {
+			return encoder.Encode(record)
+		}
+		flush = func() error { return nil }
+	default:
+		return domain.ErrUnsupportedFormat
+	}
+
+	batch := *filter
+	batch.Limit = transferBatchSize
+
+	afterID := ""
+	for {
+		customers, err := cu.customerRepo.FindAfter(ctx, &batch, afterID)
+		if err != nil {
+			return err
+		}
+
+		for _, customer := range customers {
+			if err := write(&customerRecord{
+				ID:        customer.ID,
+				Name:      customer.Name,
+				Age:       customer.Age,
+				CreatedAt: customer.CreatedAt,
+				UpdatedAt: customer.UpdatedAt,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+
+		if len(customers) < transferBatchSize {
+			return nil
+		}
+		afterID = customers[len(customers)-1].ID
+	}
+}
+
+// ImportCustomers reads customers from r and inserts the valid rows in batches. Invalid rows are
+// skipped and reported by their line number. mapping renames source columns (or NDJSON keys) to
+// the customer fields name and age.
+func (cu *customerUsecase) ImportCustomers(ctx context.Context, r io.Reader, format string, mapping map[string]string) (*entity.ImportReport, error) {
+	importer := &customerImporter{ctx: ctx, cu: cu, report: &entity.ImportReport{Errors: []entity.ImportRowError{}}}
+
+	var err error
+	switch format {
+	case FormatCSV:
+		err = importer.readCSV(r, mapping)
+	case FormatNDJSON:
+		err = importer.readNDJSON(r, mapping)
+	default:
+		return nil, domain.ErrUnsupportedFormat
+	}
+	if err != nil {
+		return importer.report, err
+	}
+
+	if err := importer.flush(); err != nil {
+		return importer.report, err
+	}
+
+	return importer.report, nil
+}
+
+type customerImporter struct {
+	ctx     context.Context
+	cu      *customerUsecase
+	report  *entity.ImportReport
+	pending []*entity.Customer
+}
+
+func (im *customerImporter) readCSV(r io.Reader, mapping map[string]string) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return domain.ErrInvalidImportHeader
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[mapColumn(name, mapping)] = i
+	}
+	nameCol, hasName := columns["name"]
+	ageCol, hasAge := columns["age"]
+	if !hasName || !hasAge {
+		return domain.ErrInvalidImportHeader
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return err
+			}
+			im.fail(parseErr.Line, map[string]interface{}{"row": parseErr.Err.Error()})
+			continue
+		}
+
+		var name, age string
+		if nameCol < len(record) {
+			name = record[nameCol]
+		}
+		if ageCol < len(record) {
+			age = record[ageCol]
+		}
+
+		line, _ := reader.FieldPos(0)
+		if err := im.add(line, name, age); err != nil {
+			return err
+		}
+	}
+}
+
+func (im *customerImporter) readNDJSON(r io.Reader, mapping map[string]string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxNDJSONLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(text), &fields); err != nil {
+			im.fail(line, map[string]interface{}{"row": "invalid json"})
+			continue
+		}
+
+		row := map[string]string{}
+		for key, value := range fields {
+			switch v := value.(type) {
+			case string:
+				row[mapColumn(key, mapping)] = v
+			case float64:
+				row[mapColumn(key, mapping)] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+
+		if err := im.add(line, row["name"], row["age"]); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (im *customerImporter) add(line int, name, age string) error {
+	row := customerImportRow{Name: strings.TrimSpace(name)}
+
+	if age = strings.TrimSpace(age); age != "" {
+		parsed, err := strconv.Atoi(age)
+		if err != nil {
+			im.fail(line, map[string]interface{}{"Age": "Age is numeric"})
+			return nil
+		}
+		row.Age = parsed
+	}
+
+	if err := validation.Validate(row); err != nil {
+		im.fail(line, validation.ErrorResponse(err))
+		return nil
+	}
+
+	im.pending = append(im.pending, &entity.Customer{
+		ID:   util.GenerateUuid(),
+		Name: row.Name,
+		Age:  row.Age,
+	})
+	if len(im.pending) >= transferBatchSize {
+		return im.flush()
+	}
+
+	return nil
+}
+
+func (im *customerImporter) fail(line int, errs map[string]interface{}) {
+	im.report.Failed++
+	if len(im.report.Errors) < maxImportErrors {
+		im.report.Errors = append(im.report.Errors, entity.ImportRowError{Row: line, Errors: errs})
+	}
+}
+
+func (im *customerImporter) flush() error {
+	if len(im.pending) == 0 {
+		return nil
+	}
+
+	if err := im.cu.customerRepo.Transaction(im.ctx, func(repo repository.CustomerRepository) error {
+		return im.cu.createBatch(im.ctx, repo, im.pending)
+	}); err != nil {
+		return err
+	}
+
+	im.report.Imported += len(im.pending)
+	metrics.CountCustomerChanges(entity.AuditCreate, len(im.pending))
+	im.pending = im.pending[:0]
+
+	return nil
+}
+
+func mapColumn(name string, mapping map[string]string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if target, ok := mapping[name]; ok {
+		return target
+	}
+	return name
+}
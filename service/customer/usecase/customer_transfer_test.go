@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"itmx_test/domain"
+	"itmx_test/service/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportCustomers(t *testing.T) {
+	createdAt := time.Date(2024, 4, 25, 22, 17, 32, 0, time.UTC)
+	customers := []*entity.Customer{
+		{ID: "a", Name: "John Doe", Age: 23, CreatedAt: createdAt, UpdatedAt: createdAt},
+		{ID: "b", Name: "Jane Smith", Age: 44, CreatedAt: createdAt, UpdatedAt: createdAt},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindAfterFunc: func(filter *entity.CustomerFilter, afterID string) ([]*entity.Customer, error) {
+				assert.Equal(t, "", afterID)
+				assert.Equal(t, "jo", filter.Name)
+				return customers, nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		var out bytes.Buffer
+		err := usecase.ExportCustomers(context.Background(), &out, FormatCSV, &entity.CustomerFilter{Name: "jo"})
+		assert.NoError(t, err)
+		assert.Equal(t, "id,name,age,created_at,updated_at\n"+
+			"a,John Doe,23,2024-04-25T22:17:32Z,2024-04-25T22:17:32Z\n"+
+			"b,Jane Smith,44,2024-04-25T22:17:32Z,2024-04-25T22:17:32Z\n", out.String())
+	})
+
+	t.Run("ndjson across batches", func(t *testing.T) {
+		full := make([]*entity.Customer, transferBatchSize)
+		for i := range full {
+			full[i] = &entity.Customer{ID: "x", Name: "n", Age: 1}
+		}
+		full[len(full)-1].ID = "last"
+
+		calls := 0
+		repo := &mockCustomerRepo{
+			FindAfterFunc: func(filter *entity.CustomerFilter, afterID string) ([]*entity.Customer, error) {
+				calls++
+				if calls == 1 {
+					assert.Equal(t, "", afterID)
+					return full, nil
+				}
+				assert.Equal(t, "last", afterID)
+				return customers[:1], nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		var out bytes.Buffer
+		err := usecase.ExportCustomers(context.Background(), &out, FormatNDJSON, &entity.CustomerFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Len(t, lines, transferBatchSize+1)
+		assert.Equal(t, `{"id":"a","name":"John Doe","age":23,"created_at":"2024-04-25T22:17:32Z","updated_at":"2024-04-25T22:17:32Z"}`, lines[len(lines)-1])
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		usecase := NewCustomerUsecase(&mockCustomerRepo{})
+
+		err := usecase.ExportCustomers(context.Background(), &bytes.Buffer{}, "xml", &entity.CustomerFilter{})
+		assert.Equal(t, domain.ErrUnsupportedFormat, err)
+	})
+}
+
+func TestImportCustomers(t *testing.T) {
+	t.Run("csv with mapping and invalid rows", func(t *testing.T) {
+		var created []*entity.Customer
+		repo := &mockCustomerRepo{
+			CreateBatchFunc: func(customers []*entity.Customer) error {
+				created = append(created, customers...)
+				return nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		input := "Full Name,Years\nJohn Doe,23\n,30\nJane Smith,abc\nSomchai Jaidee,150\nMalee Suksai,31\n"
+		report, err := usecase.ImportCustomers(context.Background(), strings.NewReader(input), FormatCSV, map[string]string{"full name": "name", "years": "age"})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.Imported)
+		assert.Equal(t, 3, report.Failed)
+		assert.Equal(t, []entity.ImportRowError{
+			{Row: 3, Errors: map[string]interface{}{"Name": "Name is required"}},
+			{Row: 4, Errors: map[string]interface{}{"Age": "Age is numeric"}},
+			{Row: 5, Errors: map[string]interface{}{"Age": "Age is max"}},
+		}, report.Errors)
+
+		assert.Len(t, created, 2)
+		assert.Equal(t, "Malee Suksai", created[1].Name)
+		assert.NotEmpty(t, created[1].ID)
+	})
+
+	t.Run("csv missing column", func(t *testing.T) {
+		usecase := NewCustomerUsecase(&mockCustomerRepo{})
+
+		_, err := usecase.ImportCustomers(context.Background(), strings.NewReader("name\nJohn\n"), FormatCSV, nil)
+		assert.Equal(t, domain.ErrInvalidImportHeader, err)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		var created []*entity.Customer
+		repo := &mockCustomerRepo{
+			CreateBatchFunc: func(customers []*entity.Customer) error {
+				created = append(created, customers...)
+				return nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		input := `{"name":"John Doe","age":23}` + "\n" + `{"name":` + "\n\n" + `{"name":"Jane Smith","age":"44"}` + "\n"
+		report, err := usecase.ImportCustomers(context.Background(), strings.NewReader(input), FormatNDJSON, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.Imported)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, 2, report.Errors[0].Row)
+		assert.Equal(t, 44, created[1].Age)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		expectedErr := domain.ErrInternalServerError
+		repo := &mockCustomerRepo{
+			CreateBatchFunc: func(customers []*entity.Customer) error {
+				return expectedErr
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		report, err := usecase.ImportCustomers(context.Background(), strings.NewReader("name,age\nJohn,20\n"), FormatCSV, nil)
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, 0, report.Imported)
+	})
+}
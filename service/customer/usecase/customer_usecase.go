@@ -1,16 +1,33 @@
 package usecase
 
 import (
-	"itmx_test/service/entity"
+	"context"
+	"io"
+	"math"
+
+	"itmx_test/domain"
+	"itmx_test/metrics"
 	"itmx_test/service/customer/repository"
+	"itmx_test/service/entity"
 	"itmx_test/util"
 )
 
 type CustomerUsecase interface {
-	CreateCustomer(customer *entity.Customer) error
-	GetCustomerByID(id string) (*entity.Customer, error)
-	UpdateCustomerByID(customer *entity.Customer, id string) error
-	DelCustomerByID(id string) error
+	CreateCustomer(ctx context.Context, customer *entity.Customer) error
+	CreateCustomers(ctx context.Context, customers []*entity.Customer) error
+	GetCustomerByID(ctx context.Context, id string) (*entity.Customer, error)
+	GetCustomers(ctx context.Context, filter *entity.CustomerFilter) (*entity.CustomerPage, error)
+	GetDeletedCustomers(ctx context.Context, filter *entity.CustomerFilter) (*entity.CustomerPage, error)
+	GetCustomersByCursor(ctx context.Context, filter *entity.CustomerFilter, cursor string) (*entity.CustomerCursorPage, error)
+	SearchCustomers(ctx context.Context, term string, limit int) ([]*entity.CustomerSearchResult, error)
+	ExportCustomers(ctx context.Context, w io.Writer, format string, filter *entity.CustomerFilter) error
+	ImportCustomers(ctx context.Context, r io.Reader, format string, mapping map[string]string) (*entity.ImportReport, error)
+	SeedCustomers(ctx context.Context, customers []*entity.Customer) (*entity.SeedReport, error)
+	UpdateCustomerByID(ctx context.Context, customer *entity.Customer, id string) error
+	DelCustomerByID(ctx context.Context, id string, version int) error
+	RestoreCustomerByID(ctx context.Context, id string) error
+	PurgeCustomerByID(ctx context.Context, id string) error
+	GetCustomerHistory(ctx context.Context, id string) ([]*entity.CustomerAudit, error)
 }
 
 type customerUsecase struct {
@@ -18,23 +35,74 @@ type customerUsecase struct {
 }
 
 func NewCustomerUsecase(customerRepo repository.CustomerRepository) CustomerUsecase {
-	return &customerUsecase{customerRepo}
+	return &customerUsecase{customerRepo: customerRepo}
 }
 
-func (cu *customerUsecase) CreateCustomer(customer *entity.Customer) error {
+func (cu *customerUsecase) CreateCustomer(ctx context.Context, customer *entity.Customer) error {
 	// generate uuid
 	uuid := util.GenerateUuid()
 	customer.ID = uuid
 
-	if err := cu.customerRepo.Create(customer); err != nil {
+	err := cu.customerRepo.Transaction(ctx, func(repo repository.CustomerRepository) error {
+		if err := repo.Create(ctx, customer); err != nil {
+			return err
+		}
+
+		return cu.recordChanges(ctx, repo, customerChange{
+			customerID: customer.ID,
+			operation:  entity.AuditCreate,
+			changes:    customerChanges(nil, customer),
+			customer:   customer,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics.CountCustomerChanges(entity.AuditCreate, 1)
+	return nil
+}
+
+func (cu *customerUsecase) CreateCustomers(ctx context.Context, customers []*entity.Customer) error {
+	if len(customers) == 0 {
+		return nil
+	}
+
+	for _, customer := range customers {
+		customer.ID = util.GenerateUuid()
+	}
+
+	err := cu.customerRepo.Transaction(ctx, func(repo repository.CustomerRepository) error {
+		return cu.createBatch(ctx, repo, customers)
+	})
+	if err != nil {
 		return err
 	}
 
+	metrics.CountCustomerChanges(entity.AuditCreate, len(customers))
 	return nil
 }
 
-func (cu *customerUsecase) GetCustomerByID(id string) (*entity.Customer, error) {
-	customerExist, err := cu.customerRepo.FindByID(id)
+func (cu *customerUsecase) createBatch(ctx context.Context, repo repository.CustomerRepository, customers []*entity.Customer) error {
+	if err := repo.CreateBatch(ctx, customers); err != nil {
+		return err
+	}
+
+	changes := make([]customerChange, 0, len(customers))
+	for _, customer := range customers {
+		changes = append(changes, customerChange{
+			customerID: customer.ID,
+			operation:  entity.AuditCreate,
+			changes:    customerChanges(nil, customer),
+			customer:   customer,
+		})
+	}
+
+	return cu.recordChanges(ctx, repo, changes...)
+}
+
+func (cu *customerUsecase) GetCustomerByID(ctx context.Context, id string) (*entity.Customer, error) {
+	customerExist, err := cu.customerRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -42,31 +110,226 @@ func (cu *customerUsecase) GetCustomerByID(id string) (*entity.Customer, error)
 	return customerExist, nil
 }
 
-func (cu *customerUsecase) UpdateCustomerByID(customer *entity.Customer, id string) error {
-	customerExist, err := cu.customerRepo.FindByID(id)
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+func normalizeLimit(limit int) int {
+	if limit < 1 {
+		return defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		return maxPageLimit
+	}
+	return limit
+}
+
+func normalizePage(filter *entity.CustomerFilter) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	filter.Limit = normalizeLimit(filter.Limit)
+}
+
+func newPageMeta(filter *entity.CustomerFilter, total int64) entity.PageMeta {
+	meta := entity.PageMeta{
+		Page:       filter.Page,
+		Limit:      filter.Limit,
+		Total:      total,
+		TotalPages: int(math.Ceil(float64(total) / float64(filter.Limit))),
+	}
+	if meta.Page < meta.TotalPages {
+		next := meta.Page + 1
+		meta.NextPage = &next
+	}
+	if meta.Page > 1 {
+		prev := meta.Page - 1
+		if prev > meta.TotalPages {
+			prev = meta.TotalPages
+		}
+		if prev > 0 {
+			meta.PrevPage = &prev
+		}
+	}
+
+	return meta
+}
+
+func (cu *customerUsecase) GetCustomers(ctx context.Context, filter *entity.CustomerFilter) (*entity.CustomerPage, error) {
+	normalizePage(filter)
+
+	customers, total, err := cu.customerRepo.FindAll(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.CustomerPage{Data: customers, Meta: newPageMeta(filter, total)}, nil
+}
+
+func (cu *customerUsecase) GetDeletedCustomers(ctx context.Context, filter *entity.CustomerFilter) (*entity.CustomerPage, error) {
+	normalizePage(filter)
+
+	customers, total, err := cu.customerRepo.FindDeleted(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.CustomerPage{Data: customers, Meta: newPageMeta(filter, total)}, nil
+}
+
+func (cu *customerUsecase) GetCustomersByCursor(ctx context.Context, filter *entity.CustomerFilter, cursor string) (*entity.CustomerCursorPage, error) {
+	afterID := ""
+	if cursor != "" {
+		id, err := util.DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		afterID = id
+	}
+
+	limit := normalizeLimit(filter.Limit)
+
+	// fetch one extra row to find out whether another page exists
+	filter.Limit = limit + 1
+	customers, err := cu.customerRepo.FindAfter(ctx, filter, afterID)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := entity.CursorMeta{Limit: limit}
+	if len(customers) > limit {
+		customers = customers[:limit]
+		meta.HasMore = true
+		next, err := util.EncodeCursor(customers[limit-1].ID)
+		if err != nil {
+			return nil, err
+		}
+		meta.NextCursor = next
+	}
+
+	return &entity.CustomerCursorPage{Data: customers, Meta: meta}, nil
+}
+
+func (cu *customerUsecase) SearchCustomers(ctx context.Context, term string, limit int) ([]*entity.CustomerSearchResult, error) {
+	results, err := cu.customerRepo.Search(ctx, term, normalizeLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// UpdateCustomerByID replaces the customer fields. When customer.Version is set it must match the
+// stored version. On success customer is refreshed with the stored state, including the new version.
+func (cu *customerUsecase) UpdateCustomerByID(ctx context.Context, customer *entity.Customer, id string) error {
+	err := cu.customerRepo.Transaction(ctx, func(repo repository.CustomerRepository) error {
+		customerExist, err := repo.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if customer.Version != 0 && customer.Version != customerExist.Version {
+			return domain.ErrVersionMismatch
+		}
+
+		before := *customerExist
+		customerExist.Name = customer.Name
+		customerExist.Age = customer.Age
+
+		if err := repo.Update(ctx, customerExist); err != nil {
+			return err
+		}
+
+		if err := cu.recordChanges(ctx, repo, customerChange{
+			customerID: id,
+			operation:  entity.AuditUpdate,
+			changes:    customerChanges(&before, customerExist),
+			customer:   customerExist,
+		}); err != nil {
+			return err
+		}
+
+		*customer = *customerExist
+
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	customerExist.Name = customer.Name
-	customerExist.Age = customer.Age
+	metrics.CountCustomerChanges(entity.AuditUpdate, 1)
+	return nil
+}
 
-	if err := cu.customerRepo.Update(customerExist); err != nil {
+// DelCustomerByID soft deletes the customer, a non zero version must match the stored version
+func (cu *customerUsecase) DelCustomerByID(ctx context.Context, id string, version int) error {
+	err := cu.customerRepo.Transaction(ctx, func(repo repository.CustomerRepository) error {
+		customerExist, err := repo.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if version != 0 && version != customerExist.Version {
+			return domain.ErrVersionMismatch
+		}
+
+		if err := repo.DeleteByID(ctx, customerExist.ID, version); err != nil {
+			return err
+		}
+
+		return cu.recordChanges(ctx, repo, customerChange{
+			customerID: customerExist.ID,
+			operation:  entity.AuditDelete,
+			changes:    deletedChange,
+			customer:   customerExist,
+		})
+	})
+	if err != nil {
 		return err
 	}
 
+	metrics.CountCustomerChanges(entity.AuditDelete, 1)
 	return nil
 }
 
-func (cu *customerUsecase) DelCustomerByID(id string) error {
-	customerExist, err := cu.customerRepo.FindByID(id)
+func (cu *customerUsecase) RestoreCustomerByID(ctx context.Context, id string) error {
+	err := cu.customerRepo.Transaction(ctx, func(repo repository.CustomerRepository) error {
+		if err := repo.Restore(ctx, id); err != nil {
+			return err
+		}
+
+		return cu.recordChanges(ctx, repo, customerChange{
+			customerID: id,
+			operation:  entity.AuditRestore,
+			changes:    restoredChange,
+		})
+	})
 	if err != nil {
 		return err
 	}
 
-	if err := cu.customerRepo.DeleteByID(customerExist.ID); err != nil {
+	metrics.CountCustomerChanges(entity.AuditRestore, 1)
+	return nil
+}
+
+// PurgeCustomerByID permanently removes the customer, whether or not it was soft deleted.
+// Its audit history is kept.
+func (cu *customerUsecase) PurgeCustomerByID(ctx context.Context, id string) error {
+	err := cu.customerRepo.Transaction(ctx, func(repo repository.CustomerRepository) error {
+		if err := repo.Purge(ctx, id); err != nil {
+			return err
+		}
+
+		return cu.recordChanges(ctx, repo, customerChange{
+			customerID: id,
+			operation:  entity.AuditPurge,
+		})
+	})
+	if err != nil {
 		return err
 	}
 
+	metrics.CountCustomerChanges(entity.AuditPurge, 1)
 	return nil
 }
@@ -1,49 +1,157 @@
 package usecase
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 
 	"itmx_test/domain"
+	"itmx_test/metrics"
+	"itmx_test/service/customer/repository"
 	"itmx_test/service/entity"
+	"itmx_test/util"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type mockCustomerRepo struct {
-	CreateFunc     func(customer *entity.Customer) error
-	FindByIDFunc   func(id string) (*entity.Customer, error)
-	UpdateFunc     func(customer *entity.Customer) error
-	DeleteByIDFunc func(id string) error
+	CreateFunc      func(customer *entity.Customer) error
+	CreateBatchFunc func(customers []*entity.Customer) error
+	FindByIDFunc    func(id string) (*entity.Customer, error)
+	FindByNamesFunc func(names []string) ([]*entity.Customer, error)
+	FindAllFunc     func(filter *entity.CustomerFilter) ([]*entity.Customer, int64, error)
+	FindAfterFunc   func(filter *entity.CustomerFilter, afterID string) ([]*entity.Customer, error)
+	FindDeletedFunc func(filter *entity.CustomerFilter) ([]*entity.Customer, int64, error)
+	ExistsFunc      func(id string) (bool, error)
+	SearchFunc      func(term string, limit int) ([]*entity.CustomerSearchResult, error)
+	UpdateFunc      func(customer *entity.Customer) error
+	DeleteByIDFunc  func(id string, version int) error
+	RestoreFunc     func(id string) error
+	PurgeFunc       func(id string) error
+	FindAuditsFunc  func(id string) ([]*entity.CustomerAudit, error)
+
+	// audits and events collect every audit record and outbox event written through the mock
+	audits []*entity.CustomerAudit
+	events []*entity.OutboxEvent
 }
 
-func (m *mockCustomerRepo) Create(customer *entity.Customer) error {
+func (m *mockCustomerRepo) Create(ctx context.Context, customer *entity.Customer) error {
 	if m.CreateFunc != nil {
 		return m.CreateFunc(customer)
 	}
 	return nil
 }
 
-func (m *mockCustomerRepo) FindByID(id string) (*entity.Customer, error) {
+func (m *mockCustomerRepo) CreateBatch(ctx context.Context, customers []*entity.Customer) error {
+	if m.CreateBatchFunc != nil {
+		return m.CreateBatchFunc(customers)
+	}
+	return nil
+}
+
+func (m *mockCustomerRepo) FindByID(ctx context.Context, id string) (*entity.Customer, error) {
 	if m.FindByIDFunc != nil {
 		return m.FindByIDFunc(id)
 	}
 	return nil, nil
 }
 
-func (m *mockCustomerRepo) Update(customer *entity.Customer) error {
+func (m *mockCustomerRepo) FindByNames(ctx context.Context, names []string) ([]*entity.Customer, error) {
+	if m.FindByNamesFunc != nil {
+		return m.FindByNamesFunc(names)
+	}
+	return nil, nil
+}
+
+func (m *mockCustomerRepo) FindAll(ctx context.Context, filter *entity.CustomerFilter) ([]*entity.Customer, int64, error) {
+	if m.FindAllFunc != nil {
+		return m.FindAllFunc(filter)
+	}
+	return nil, 0, nil
+}
+
+func (m *mockCustomerRepo) FindAfter(ctx context.Context, filter *entity.CustomerFilter, afterID string) ([]*entity.Customer, error) {
+	if m.FindAfterFunc != nil {
+		return m.FindAfterFunc(filter, afterID)
+	}
+	return nil, nil
+}
+
+func (m *mockCustomerRepo) FindDeleted(ctx context.Context, filter *entity.CustomerFilter) ([]*entity.Customer, int64, error) {
+	if m.FindDeletedFunc != nil {
+		return m.FindDeletedFunc(filter)
+	}
+	return nil, 0, nil
+}
+
+func (m *mockCustomerRepo) Exists(ctx context.Context, id string) (bool, error) {
+	if m.ExistsFunc != nil {
+		return m.ExistsFunc(id)
+	}
+	return false, nil
+}
+
+func (m *mockCustomerRepo) Search(ctx context.Context, term string, limit int) ([]*entity.CustomerSearchResult, error) {
+	if m.SearchFunc != nil {
+		return m.SearchFunc(term, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockCustomerRepo) Update(ctx context.Context, customer *entity.Customer) error {
 	if m.UpdateFunc != nil {
 		return m.UpdateFunc(customer)
 	}
 	return nil
 }
 
-func (m *mockCustomerRepo) DeleteByID(id string) error {
+func (m *mockCustomerRepo) DeleteByID(ctx context.Context, id string, version int) error {
 	if m.DeleteByIDFunc != nil {
-		return m.DeleteByIDFunc(id)
+		return m.DeleteByIDFunc(id, version)
 	}
 	return nil
 }
 
+func (m *mockCustomerRepo) Restore(ctx context.Context, id string) error {
+	if m.RestoreFunc != nil {
+		return m.RestoreFunc(id)
+	}
+	return nil
+}
+
+func (m *mockCustomerRepo) Purge(ctx context.Context, id string) error {
+	if m.PurgeFunc != nil {
+		return m.PurgeFunc(id)
+	}
+	return nil
+}
+
+func (m *mockCustomerRepo) CreateAudits(ctx context.Context, audits []*entity.CustomerAudit) error {
+	m.audits = append(m.audits, audits...)
+	return nil
+}
+
+func (m *mockCustomerRepo) CreateOutboxEvents(ctx context.Context, events []*entity.OutboxEvent) error {
+	m.events = append(m.events, events...)
+	return nil
+}
+
+func (m *mockCustomerRepo) FindAuditsByCustomerID(ctx context.Context, id string) ([]*entity.CustomerAudit, error) {
+	if m.FindAuditsFunc != nil {
+		return m.FindAuditsFunc(id)
+	}
+	return nil, nil
+}
+
+func (m *mockCustomerRepo) Transaction(ctx context.Context, fn func(repo repository.CustomerRepository) error) error {
+	return fn(m)
+}
+
 func TestCreateCustomer(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		repo := &mockCustomerRepo{
@@ -53,11 +161,66 @@ func TestCreateCustomer(t *testing.T) {
 		}
 		usecase := NewCustomerUsecase(repo)
 
-		err := usecase.CreateCustomer(&entity.Customer{Name: "test", Age: 11})
+		err := usecase.CreateCustomer(context.Background(), &entity.Customer{Name: "test", Age: 11})
 		assert.NoError(t, err)
 	})
 }
 
+// countedChanges reads the committed customer changes of operation from the metrics registry
+func countedChanges(t *testing.T, operation string) float64 {
+	families, err := metrics.Registry.Gather()
+	assert.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "itmx_customer_changes_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "operation" && label.GetValue() == operation {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestCreateCustomers(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		counted := countedChanges(t, entity.AuditCreate)
+		repo := &mockCustomerRepo{
+			CreateBatchFunc: func(customers []*entity.Customer) error {
+				assert.Len(t, customers, 2)
+				return nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		customers := []*entity.Customer{{Name: "a", Age: 1}, {Name: "b", Age: 2}}
+		err := usecase.CreateCustomers(context.Background(), customers)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, customers[0].ID)
+		assert.NotEqual(t, customers[0].ID, customers[1].ID)
+		assert.Equal(t, counted+2, countedChanges(t, entity.AuditCreate))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		counted := countedChanges(t, entity.AuditCreate)
+		expectedErr := domain.ErrInternalServerError
+		repo := &mockCustomerRepo{
+			CreateBatchFunc: func(customers []*entity.Customer) error {
+				return expectedErr
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		err := usecase.CreateCustomers(context.Background(), []*entity.Customer{{Name: "a", Age: 1}})
+		assert.Equal(t, expectedErr, err)
+		// nothing was committed
+		assert.Equal(t, counted, countedChanges(t, entity.AuditCreate))
+	})
+}
+
 func TestGetCustomerByID(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		expectedCustomer := &entity.Customer{Name: "test", Age: 11}
@@ -68,7 +231,7 @@ func TestGetCustomerByID(t *testing.T) {
 		}
 		usecase := NewCustomerUsecase(repo)
 
-		customer, err := usecase.GetCustomerByID("123")
+		customer, err := usecase.GetCustomerByID(context.Background(), "123")
 		assert.NoError(t, err)
 		assert.Equal(t, expectedCustomer, customer)
 	})
@@ -82,13 +245,162 @@ func TestGetCustomerByID(t *testing.T) {
 		}
 		usecase := NewCustomerUsecase(repo)
 
-		customer, err := usecase.GetCustomerByID("123")
+		customer, err := usecase.GetCustomerByID(context.Background(), "123")
 		assert.Error(t, err)
 		assert.Nil(t, customer)
 		assert.Equal(t, expectedErr, err)
 	})
 }
 
+func TestGetCustomers(t *testing.T) {
+	t.Run("success with defaults", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindAllFunc: func(filter *entity.CustomerFilter) ([]*entity.Customer, int64, error) {
+				assert.Equal(t, 1, filter.Page)
+				assert.Equal(t, 20, filter.Limit)
+				return []*entity.Customer{{Name: "test", Age: 11}}, 45, nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		page, err := usecase.GetCustomers(context.Background(), &entity.CustomerFilter{})
+		assert.NoError(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, int64(45), page.Meta.Total)
+		assert.Equal(t, 3, page.Meta.TotalPages)
+		assert.Equal(t, 2, *page.Meta.NextPage)
+		assert.Nil(t, page.Meta.PrevPage)
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindAllFunc: func(filter *entity.CustomerFilter) ([]*entity.Customer, int64, error) {
+				assert.Equal(t, 100, filter.Limit)
+				return []*entity.Customer{}, 250, nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		page, err := usecase.GetCustomers(context.Background(), &entity.CustomerFilter{Page: 3, Limit: 500})
+		assert.NoError(t, err)
+		assert.Nil(t, page.Meta.NextPage)
+		assert.Equal(t, 2, *page.Meta.PrevPage)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		expectedErr := domain.ErrInternalServerError
+		repo := &mockCustomerRepo{
+			FindAllFunc: func(filter *entity.CustomerFilter) ([]*entity.Customer, int64, error) {
+				return nil, 0, expectedErr
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		page, err := usecase.GetCustomers(context.Background(), &entity.CustomerFilter{})
+		assert.Nil(t, page)
+		assert.Equal(t, expectedErr, err)
+	})
+}
+
+func TestGetCustomersByCursor(t *testing.T) {
+	util.SetCursorSecret("test-cursor-secret-0123")
+	t.Cleanup(func() { util.SetCursorSecret("") })
+
+	t.Run("first page with more rows", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindAfterFunc: func(filter *entity.CustomerFilter, afterID string) ([]*entity.Customer, error) {
+				assert.Equal(t, "", afterID)
+				assert.Equal(t, 3, filter.Limit)
+				return []*entity.Customer{{ID: "a"}, {ID: "b"}, {ID: "c"}}, nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		page, err := usecase.GetCustomersByCursor(context.Background(), &entity.CustomerFilter{Limit: 2}, "")
+		assert.NoError(t, err)
+		assert.Len(t, page.Data, 2)
+		assert.True(t, page.Meta.HasMore)
+
+		afterID, err := util.DecodeCursor(page.Meta.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, "b", afterID)
+	})
+
+	t.Run("next page from cursor", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindAfterFunc: func(filter *entity.CustomerFilter, afterID string) ([]*entity.Customer, error) {
+				assert.Equal(t, "b", afterID)
+				return []*entity.Customer{{ID: "c"}}, nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		cursor, err := util.EncodeCursor("b")
+		assert.NoError(t, err)
+
+		page, err := usecase.GetCustomersByCursor(context.Background(), &entity.CustomerFilter{Limit: 2}, cursor)
+		assert.NoError(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.False(t, page.Meta.HasMore)
+		assert.Empty(t, page.Meta.NextCursor)
+	})
+
+	t.Run("tampered cursor", func(t *testing.T) {
+		usecase := NewCustomerUsecase(&mockCustomerRepo{})
+
+		cursor, _ := util.EncodeCursor("b")
+		page, err := usecase.GetCustomersByCursor(context.Background(), &entity.CustomerFilter{}, "x"+cursor)
+		assert.Nil(t, page)
+		assert.Equal(t, domain.ErrInvalidCursor, err)
+	})
+
+	t.Run("no secret set", func(t *testing.T) {
+		cursor, _ := util.EncodeCursor("b")
+		util.SetCursorSecret("")
+		defer util.SetCursorSecret("test-cursor-secret-0123")
+
+		_, err := util.EncodeCursor("b")
+		assert.Error(t, err)
+
+		page, err := NewCustomerUsecase(&mockCustomerRepo{}).GetCustomersByCursor(context.Background(), &entity.CustomerFilter{}, cursor)
+		assert.Nil(t, page)
+		assert.Error(t, err)
+		assert.Equal(t, domain.ErrInternalServerError, domain.Lookup(err))
+	})
+}
+
+func TestSearchCustomers(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		expectedResults := []*entity.CustomerSearchResult{{Customer: &entity.Customer{Name: "John Doe"}, Snippet: "<mark>John</mark> Doe"}}
+		repo := &mockCustomerRepo{
+			SearchFunc: func(term string, limit int) ([]*entity.CustomerSearchResult, error) {
+				assert.Equal(t, "jo", term)
+				assert.Equal(t, 20, limit)
+				return expectedResults, nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		results, err := usecase.SearchCustomers(context.Background(), "jo", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedResults, results)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		expectedErr := domain.ErrInternalServerError
+		repo := &mockCustomerRepo{
+			SearchFunc: func(term string, limit int) ([]*entity.CustomerSearchResult, error) {
+				return nil, expectedErr
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		results, err := usecase.SearchCustomers(context.Background(), "jo", 10)
+		assert.Nil(t, results)
+		assert.Equal(t, expectedErr, err)
+	})
+}
+
 func TestUpdateCustomerByID(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		expectedCustomer := &entity.Customer{Name: "updated", Age: 30}
@@ -104,7 +416,7 @@ func TestUpdateCustomerByID(t *testing.T) {
 		}
 		usecase := NewCustomerUsecase(repo)
 
-		err := usecase.UpdateCustomerByID(expectedCustomer, "123")
+		err := usecase.UpdateCustomerByID(context.Background(), expectedCustomer, "123")
 		assert.NoError(t, err)
 	})
 
@@ -121,9 +433,45 @@ func TestUpdateCustomerByID(t *testing.T) {
 		}
 		usecase := NewCustomerUsecase(repo)
 
-		err := usecase.UpdateCustomerByID(updateCustomer, "123")
+		err := usecase.UpdateCustomerByID(context.Background(), updateCustomer, "123")
 		assert.Equal(t, expectedErr, err)
 	})
+
+	t.Run("stale version", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindByIDFunc: func(id string) (*entity.Customer, error) {
+				return &entity.Customer{Name: "test", Age: 11, Version: 3}, nil
+			},
+			UpdateFunc: func(customer *entity.Customer) error {
+				t.Fatal("update must not be called with a stale version")
+				return nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		err := usecase.UpdateCustomerByID(context.Background(), &entity.Customer{Name: "updated", Age: 30, Version: 2}, "123")
+		assert.Equal(t, domain.ErrVersionMismatch, err)
+	})
+
+	t.Run("refreshes the customer", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindByIDFunc: func(id string) (*entity.Customer, error) {
+				return &entity.Customer{ID: "123", Name: "test", Age: 11, Version: 3}, nil
+			},
+			UpdateFunc: func(customer *entity.Customer) error {
+				assert.Equal(t, 3, customer.Version)
+				customer.Version++
+				return nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		customer := &entity.Customer{Name: "updated", Age: 30, Version: 3}
+		err := usecase.UpdateCustomerByID(context.Background(), customer, "123")
+		assert.NoError(t, err)
+		assert.Equal(t, "123", customer.ID)
+		assert.Equal(t, 4, customer.Version)
+	})
 }
 
 func TestDelCustomerByID(t *testing.T) {
@@ -133,13 +481,13 @@ func TestDelCustomerByID(t *testing.T) {
 			FindByIDFunc: func(id string) (*entity.Customer, error) {
 				return expectedCustomer, nil
 			},
-			DeleteByIDFunc: func(id string) error {
+			DeleteByIDFunc: func(id string, version int) error {
 				return nil
 			},
 		}
 		usecase := NewCustomerUsecase(repo)
 
-		err := usecase.DelCustomerByID("123")
+		err := usecase.DelCustomerByID(context.Background(), "123", 0)
 		assert.NoError(t, err)
 	})
 
@@ -149,13 +497,345 @@ func TestDelCustomerByID(t *testing.T) {
 			FindByIDFunc: func(id string) (*entity.Customer, error) {
 				return nil, domain.ErrNotFound
 			},
-			DeleteByIDFunc: func(id string) error {
+			DeleteByIDFunc: func(id string, version int) error {
+				return nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		err := usecase.DelCustomerByID(context.Background(), "123", 0)
+		assert.Equal(t, expectedErr, err)
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindByIDFunc: func(id string) (*entity.Customer, error) {
+				return &entity.Customer{ID: "123", Version: 5}, nil
+			},
+			DeleteByIDFunc: func(id string, version int) error {
+				t.Fatal("delete must not be called with a stale version")
+				return nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		err := usecase.DelCustomerByID(context.Background(), "123", 4)
+		assert.Equal(t, domain.ErrVersionMismatch, err)
+	})
+}
+
+func TestGetDeletedCustomers(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindDeletedFunc: func(filter *entity.CustomerFilter) ([]*entity.Customer, int64, error) {
+				assert.Equal(t, 1, filter.Page)
+				assert.Equal(t, 20, filter.Limit)
+				return []*entity.Customer{{Name: "test", Age: 11}}, 1, nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		page, err := usecase.GetDeletedCustomers(context.Background(), &entity.CustomerFilter{})
+		assert.NoError(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, 1, page.Meta.TotalPages)
+		assert.Nil(t, page.Meta.NextPage)
+	})
+}
+
+func TestRestoreCustomerByID(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			RestoreFunc: func(id string) error {
+				assert.Equal(t, "123", id)
+				return nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		err := usecase.RestoreCustomerByID(context.Background(), "123")
+		assert.NoError(t, err)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			RestoreFunc: func(id string) error {
+				return domain.ErrNotFound
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		err := usecase.RestoreCustomerByID(context.Background(), "123")
+		assert.Equal(t, domain.ErrNotFound, err)
+	})
+}
+
+func TestPurgeCustomerByID(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			PurgeFunc: func(id string) error {
+				assert.Equal(t, "123", id)
+				return nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		err := usecase.PurgeCustomerByID(context.Background(), "123")
+		assert.NoError(t, err)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			PurgeFunc: func(id string) error {
+				return domain.ErrNotFound
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		err := usecase.PurgeCustomerByID(context.Background(), "123")
+		assert.Equal(t, domain.ErrNotFound, err)
+	})
+}
+
+func TestCustomerAudit(t *testing.T) {
+	meta := entity.AuditMeta{Actor: "alice", RequestID: "req-1"}
+	ctx := entity.ContextWithAuditMeta(context.Background(), meta)
+
+	t.Run("create", func(t *testing.T) {
+		repo := &mockCustomerRepo{}
+		usecase := NewCustomerUsecase(repo)
+
+		customer := &entity.Customer{Name: "test", Age: 11}
+		err := usecase.CreateCustomer(ctx, customer)
+		assert.NoError(t, err)
+		assert.Len(t, repo.audits, 1)
+		assert.Equal(t, &entity.CustomerAudit{
+			CustomerID: customer.ID,
+			Operation:  entity.AuditCreate,
+			Actor:      "alice",
+			RequestID:  "req-1",
+			Changes: map[string]entity.FieldChange{
+				"name": {To: "test"},
+				"age":  {To: 11},
+			},
+		}, repo.audits[0])
+	})
+
+	t.Run("update records only changed fields", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindByIDFunc: func(id string) (*entity.Customer, error) {
+				return &entity.Customer{ID: id, Name: "test", Age: 11, Version: 1}, nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		err := usecase.UpdateCustomerByID(ctx, &entity.Customer{Name: "test", Age: 12}, "123")
+		assert.NoError(t, err)
+		assert.Len(t, repo.audits, 1)
+		assert.Equal(t, entity.AuditUpdate, repo.audits[0].Operation)
+		assert.Equal(t, map[string]entity.FieldChange{"age": {From: 11, To: 12}}, repo.audits[0].Changes)
+	})
+
+	t.Run("failed update writes no audit", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindByIDFunc: func(id string) (*entity.Customer, error) {
+				return &entity.Customer{ID: id, Name: "test", Age: 11, Version: 1}, nil
+			},
+			UpdateFunc: func(customer *entity.Customer) error {
+				return domain.ErrVersionMismatch
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		err := usecase.UpdateCustomerByID(ctx, &entity.Customer{Name: "test", Age: 12}, "123")
+		assert.Equal(t, domain.ErrVersionMismatch, err)
+		assert.Empty(t, repo.audits)
+	})
+
+	t.Run("delete, restore and purge", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindByIDFunc: func(id string) (*entity.Customer, error) {
+				return &entity.Customer{ID: id}, nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		assert.NoError(t, usecase.DelCustomerByID(ctx, "123", 0))
+		assert.NoError(t, usecase.RestoreCustomerByID(ctx, "123"))
+		assert.NoError(t, usecase.PurgeCustomerByID(ctx, "123"))
+
+		operations := []string{}
+		for _, audit := range repo.audits {
+			assert.Equal(t, "123", audit.CustomerID)
+			operations = append(operations, audit.Operation)
+		}
+		assert.Equal(t, []string{entity.AuditDelete, entity.AuditRestore, entity.AuditPurge}, operations)
+	})
+
+	t.Run("history", func(t *testing.T) {
+		expectedAudits := []*entity.CustomerAudit{{ID: 1, CustomerID: "123", Operation: entity.AuditCreate}}
+		repo := &mockCustomerRepo{
+			FindAuditsFunc: func(id string) ([]*entity.CustomerAudit, error) {
+				assert.Equal(t, "123", id)
+				return expectedAudits, nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		audits, err := usecase.GetCustomerHistory(ctx, "123")
+		assert.NoError(t, err)
+		assert.Equal(t, expectedAudits, audits)
+	})
+
+	t.Run("history of a customer stored before the audit trail", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			ExistsFunc: func(id string) (bool, error) {
+				return true, nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		audits, err := usecase.GetCustomerHistory(ctx, "123")
+		assert.NoError(t, err)
+		assert.Empty(t, audits)
+	})
+
+	t.Run("history of a customer that never existed", func(t *testing.T) {
+		usecase := NewCustomerUsecase(&mockCustomerRepo{})
+
+		audits, err := usecase.GetCustomerHistory(ctx, "123")
+		assert.Nil(t, audits)
+		assert.Equal(t, domain.ErrNotFound, err)
+	})
+}
+
+func TestCustomerOutboxEvents(t *testing.T) {
+	meta := entity.AuditMeta{Actor: "alice", RequestID: "req-1"}
+	ctx := entity.ContextWithAuditMeta(context.Background(), meta)
+
+	t.Run("lifecycle events", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			FindByIDFunc: func(id string) (*entity.Customer, error) {
+				return &entity.Customer{ID: id, Name: "test", Age: 11, Version: 1}, nil
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		customer := &entity.Customer{Name: "test", Age: 11}
+		assert.NoError(t, usecase.CreateCustomer(ctx, customer))
+		assert.NoError(t, usecase.UpdateCustomerByID(ctx, &entity.Customer{Name: "test", Age: 12}, customer.ID))
+		assert.NoError(t, usecase.DelCustomerByID(ctx, customer.ID, 0))
+
+		eventTypes := []string{}
+		for _, event := range repo.events {
+			assert.Equal(t, customer.ID, event.CustomerID)
+			eventTypes = append(eventTypes, event.EventType)
+		}
+		assert.Equal(t, []string{entity.EventCustomerCreated, entity.EventCustomerUpdated, entity.EventCustomerDeleted}, eventTypes)
+
+		payload := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal([]byte(repo.events[1].Payload), &payload))
+		assert.Equal(t, entity.EventCustomerUpdated, payload["type"])
+		data := payload["data"].(map[string]interface{})
+		assert.Equal(t, "alice", data["actor"])
+		assert.Equal(t, "req-1", data["request_id"])
+		assert.Equal(t, map[string]interface{}{"age": map[string]interface{}{"from": float64(11), "to": float64(12)}}, data["changes"])
+	})
+
+	t.Run("failed write emits no event", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			CreateFunc: func(customer *entity.Customer) error {
+				return domain.ErrConflict
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		err := usecase.CreateCustomer(ctx, &entity.Customer{Name: "test", Age: 11})
+		assert.Equal(t, domain.ErrConflict, err)
+		assert.Empty(t, repo.events)
+	})
+}
+
+func TestSeedCustomers(t *testing.T) {
+	t.Run("upsert by name", func(t *testing.T) {
+		existing := []*entity.Customer{
+			{ID: "1", Name: "John Doe", Age: 23, Version: 1},
+			{ID: "2", Name: "Jane Smith", Age: 40, Version: 1},
+		}
+		var created, updated []*entity.Customer
+		repo := &mockCustomerRepo{
+			FindByNamesFunc: func(names []string) ([]*entity.Customer, error) {
+				assert.Equal(t, []string{"John Doe", "Jane Smith", "Somchai Jaidee", "Somchai Jaidee"}, names)
+				return existing, nil
+			},
+			CreateBatchFunc: func(customers []*entity.Customer) error {
+				created = customers
+				return nil
+			},
+			UpdateFunc: func(customer *entity.Customer) error {
+				updated = append(updated, customer)
 				return nil
 			},
 		}
 		usecase := NewCustomerUsecase(repo)
 
-		err := usecase.DelCustomerByID("123")
+		report, err := usecase.SeedCustomers(context.Background(), []*entity.Customer{
+			{Name: "John Doe", Age: 23},
+			{Name: "Jane Smith", Age: 44},
+			{Name: "Somchai Jaidee", Age: 30},
+			{Name: "Somchai Jaidee", Age: 31},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, &entity.SeedReport{Created: 1, Updated: 1, Unchanged: 1}, report)
+
+		assert.Len(t, created, 1)
+		assert.NotEmpty(t, created[0].ID)
+		assert.Equal(t, 31, created[0].Age)
+
+		assert.Len(t, updated, 1)
+		assert.Equal(t, "2", updated[0].ID)
+		assert.Equal(t, 44, updated[0].Age)
+
+		// one audit record per created or updated customer
+		assert.Len(t, repo.audits, 2)
+		assert.Equal(t, entity.AuditUpdate, repo.audits[0].Operation)
+		assert.Equal(t, entity.AuditCreate, repo.audits[1].Operation)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		expectedErr := domain.ErrInternalServerError
+		repo := &mockCustomerRepo{
+			FindByNamesFunc: func(names []string) ([]*entity.Customer, error) {
+				return nil, expectedErr
+			},
+		}
+		usecase := NewCustomerUsecase(repo)
+
+		_, err := usecase.SeedCustomers(context.Background(), []*entity.Customer{{Name: "a", Age: 1}})
 		assert.Equal(t, expectedErr, err)
 	})
 }
+
+func TestTracedCustomerUsecase(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	expectedErr := domain.ErrNotFound
+	repo := &mockCustomerRepo{
+		FindByIDFunc: func(id string) (*entity.Customer, error) {
+			return nil, expectedErr
+		},
+	}
+	usecase := NewTracedCustomerUsecase(NewCustomerUsecase(repo))
+
+	err := usecase.UpdateCustomerByID(context.Background(), &entity.Customer{Name: "updated", Age: 30}, "123")
+	assert.Equal(t, expectedErr, err)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "CustomerUsecase.UpdateCustomerByID", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), attribute.String("customer.id", "123"))
+}
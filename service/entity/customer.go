@@ -14,4 +14,68 @@ type Customer struct {
 	DeletedAt gorm.DeletedAt `gorm:"index;default:null"`
 	Name      string
 	Age       int
+	// Version is bumped on every update and guards against lost updates
+	Version int `gorm:"not null;default:1"`
+}
+
+// CustomerFilter holds the paging, filtering and sorting options used when listing customers
+type CustomerFilter struct {
+	Page      int
+	Limit     int
+	Name      string
+	MinAge    int
+	MaxAge    int
+	SortBy    string
+	SortOrder string
+}
+
+type PageMeta struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+	NextPage   *int  `json:"next_page"`
+	PrevPage   *int  `json:"prev_page"`
+}
+
+type CustomerPage struct {
+	Data []*Customer `json:"data"`
+	Meta PageMeta    `json:"meta"`
+}
+
+type CursorMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+type CustomerCursorPage struct {
+	Data []*Customer `json:"data"`
+	Meta CursorMeta  `json:"meta"`
+}
+
+// CustomerSearchResult is a search hit. Snippet is HTML, the escaped name with the matching
+// words in <mark> tags.
+type CustomerSearchResult struct {
+	Customer *Customer `json:"customer"`
+	Snippet  string    `json:"snippet"`
+	Rank     float64   `json:"rank"`
+}
+
+type ImportRowError struct {
+	Row    int                    `json:"row"`
+	Errors map[string]interface{} `json:"errors"`
+}
+
+// SeedReport counts what seeding did with each customer fixture
+type SeedReport struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+type ImportReport struct {
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
 }
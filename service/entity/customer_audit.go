@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// CustomerAudit is an append-only record of a change made to a customer
+type CustomerAudit struct {
+	ID         uint                   `gorm:"primaryKey;autoIncrement" json:"id"`
+	CustomerID string                 `gorm:"index;not null" json:"customer_id"`
+	Operation  string                 `gorm:"not null" json:"operation"`
+	Actor      string                 `json:"actor"`
+	RequestID  string                 `json:"request_id"`
+	Changes    map[string]FieldChange `gorm:"serializer:json" json:"changes"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditMeta describes who made a change and from which request
+type AuditMeta struct {
+	Actor     string
+	RequestID string
+}
+
+type auditMetaKey struct{}
+
+// ContextWithAuditMeta returns a copy of ctx carrying the audit meta of the request
+func ContextWithAuditMeta(ctx context.Context, meta AuditMeta) context.Context {
+	return context.WithValue(ctx, auditMetaKey{}, meta)
+}
+
+// AuditMetaFromContext returns the audit meta carried by ctx, or the zero value
+func AuditMetaFromContext(ctx context.Context) AuditMeta {
+	meta, _ := ctx.Value(auditMetaKey{}).(AuditMeta)
+	return meta
+}
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// IdempotencyRecord remembers the request behind an Idempotency-Key and, once the request
+// finished, the response that is replayed to retries. StatusCode 0 means still in progress.
+type IdempotencyRecord struct {
+	Key         string `gorm:"column:idempotency_key;primary_key"`
+	Fingerprint string `gorm:"not null"`
+	StatusCode  int    `gorm:"not null;default:0"`
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"index;not null"`
+}
+
+func (ir *IdempotencyRecord) Completed() bool {
+	return ir.StatusCode != 0
+}
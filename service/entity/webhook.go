@@ -0,0 +1,70 @@
+package entity
+
+import "time"
+
+const (
+	EventCustomerCreated = "customer.created"
+	EventCustomerUpdated = "customer.updated"
+	EventCustomerDeleted = "customer.deleted"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// OutboxEvent is written in the same transaction as the customer change it describes and
+// is later fanned out to the matching webhook subscriptions
+type OutboxEvent struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventType   string     `gorm:"not null" json:"event_type"`
+	CustomerID  string     `gorm:"index" json:"customer_id"`
+	Payload     string     `gorm:"not null" json:"payload"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ProcessedAt *time.Time `gorm:"index" json:"processed_at"`
+}
+
+type WebhookSubscription struct {
+	ID        string    `gorm:"primary_key" json:"id"`
+	URL       string    `gorm:"not null" json:"url"`
+	Secret    string    `gorm:"not null" json:"-"`
+	Events    []string  `gorm:"serializer:json" json:"events"`
+	Active    bool      `gorm:"not null;default:true" json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Subscribes reports whether the subscription wants events of the given type
+func (ws *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, event := range ws.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDelivery struct {
+	ID             uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubscriptionID string            `gorm:"index;not null" json:"subscription_id"`
+	EventID        uint              `gorm:"index;not null" json:"event_id"`
+	EventType      string            `gorm:"not null" json:"event_type"`
+	Payload        string            `gorm:"not null" json:"payload"`
+	Status         string            `gorm:"index;not null" json:"status"`
+	Attempts       int               `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time         `gorm:"index" json:"next_attempt_at"`
+	LastError      string            `json:"last_error,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	AttemptLog     []*WebhookAttempt `gorm:"foreignKey:DeliveryID" json:"attempt_log,omitempty"`
+}
+
+type WebhookAttempt struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	DeliveryID uint      `gorm:"index;not null" json:"delivery_id"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
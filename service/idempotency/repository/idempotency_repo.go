@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"itmx_test/domain"
+	"itmx_test/service/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	FindByKey(ctx context.Context, key string, now time.Time) (*entity.IdempotencyRecord, error)
+	Reserve(ctx context.Context, record *entity.IdempotencyRecord) error
+	Complete(ctx context.Context, record *entity.IdempotencyRecord) error
+	Release(ctx context.Context, record *entity.IdempotencyRecord) error
+}
+
+type idempotencyRepo struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepo{db}
+}
+
+// FindByKey returns the unexpired record stored under key
+func (ir *idempotencyRepo) FindByKey(ctx context.Context, key string, now time.Time) (*entity.IdempotencyRecord, error) {
+	record := &entity.IdempotencyRecord{}
+	if err := ir.db.WithContext(ctx).Where("idempotency_key = ? AND expires_at > ?", key, now).First(record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+// Reserve claims the key for an in-progress request, sweeping expired records first. It
+// returns domain.ErrConflict when another request holds the key.
+func (ir *idempotencyRepo) Reserve(ctx context.Context, record *entity.IdempotencyRecord) error {
+	return ir.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", record.CreatedAt).Delete(&entity.IdempotencyRecord{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrConflict
+		}
+		return nil
+	})
+}
+
+// Complete stores the response of the request that reserved the key and extends the record
+// from the lease of the reservation to its expiry. It returns domain.ErrConflict when the
+// reservation is gone, a retry may have reserved the key again once the lease ran out.
+func (ir *idempotencyRepo) Complete(ctx context.Context, record *entity.IdempotencyRecord) error {
+	result := ir.db.WithContext(ctx).Model(&entity.IdempotencyRecord{}).
+		Where("idempotency_key = ? AND created_at = ?", record.Key, record.CreatedAt).
+		Select("status_code", "content_type", "body", "expires_at").
+		Updates(record)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrConflict
+	}
+	return nil
+}
+
+// Release frees the key so the request can be retried, used when it failed without a
+// response worth replaying. Like Complete it only touches the record's own reservation and
+// returns domain.ErrConflict when it is gone.
+func (ir *idempotencyRepo) Release(ctx context.Context, record *entity.IdempotencyRecord) error {
+	result := ir.db.WithContext(ctx).
+		Where("idempotency_key = ? AND created_at = ?", record.Key, record.CreatedAt).
+		Delete(&entity.IdempotencyRecord{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrConflict
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"itmx_test/config"
+	"itmx_test/domain"
+	"itmx_test/service/entity"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestReserve(t *testing.T) {
+	// Mock database
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer sqlDB.Close()
+
+	// Expectation for the sqlite version check
+	mock.ExpectQuery("select sqlite_version()").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.31.1"))
+
+	dialector := sqlite.Dialector{Conn: sqlDB}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm database: %v", err)
+	}
+
+	repo := NewIdempotencyRepository(gormDB)
+
+	now := time.Now()
+	record := func() *entity.IdempotencyRecord {
+		return &entity.IdempotencyRecord{Key: "key-1", Fingerprint: "abc", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	}
+
+	// Success case
+	t.Run("success", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `idempotency_records` WHERE expires_at <= \\?").WithArgs(now).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("INSERT INTO `idempotency_records` .* ON CONFLICT DO NOTHING").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := repo.Reserve(context.Background(), record())
+		assert.NoError(t, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Key held by another request
+	t.Run("conflict", func(t *testing.T) {
+		// Setup expectations
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `idempotency_records`").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO `idempotency_records`").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.Reserve(context.Background(), record())
+		assert.Equal(t, domain.ErrConflict, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestFindByKey(t *testing.T) {
+	// Mock database
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer sqlDB.Close()
+
+	// Expectation for the sqlite version check
+	mock.ExpectQuery("select sqlite_version()").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.31.1"))
+
+	dialector := sqlite.Dialector{Conn: sqlDB}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm database: %v", err)
+	}
+
+	repo := NewIdempotencyRepository(gormDB)
+	now := time.Now()
+
+	// Not found case
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM `idempotency_records`").WillReturnRows(sqlmock.NewRows([]string{"idempotency_key"}))
+
+		_, err := repo.FindByKey(context.Background(), "key-1", now)
+		assert.Equal(t, domain.ErrNotFound, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// A database failure is not a cache miss
+	t.Run("failure", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM `idempotency_records`").WillReturnError(gorm.ErrInvalidDB)
+
+		_, err := repo.FindByKey(context.Background(), "key-1", now)
+		assert.ErrorIs(t, err, gorm.ErrInvalidDB)
+		assert.NotErrorIs(t, err, domain.ErrNotFound)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReservationOwnership(t *testing.T) {
+	db, err := config.InitDB(config.DatabaseConfig{Driver: config.DriverMemory, AutoMigrate: true})
+	if err != nil {
+		t.Fatalf("Failed to open sqlite database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+
+	repo := NewIdempotencyRepository(db)
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	first := &entity.IdempotencyRecord{Key: "key-1", Fingerprint: "abc", CreatedAt: now, ExpiresAt: now.Add(time.Minute)}
+	assert.NoError(t, repo.Reserve(ctx, first))
+
+	// the first request outlives its lease and a retry reserves the key again
+	later := now.Add(2 * time.Minute)
+	retry := &entity.IdempotencyRecord{Key: "key-1", Fingerprint: "abc", CreatedAt: later, ExpiresAt: later.Add(time.Minute)}
+	assert.NoError(t, repo.Reserve(ctx, retry))
+
+	first.StatusCode = 201
+	first.ExpiresAt = later.Add(time.Hour)
+	assert.Equal(t, domain.ErrConflict, repo.Complete(ctx, first))
+	assert.Equal(t, domain.ErrConflict, repo.Release(ctx, first))
+
+	stored, err := repo.FindByKey(ctx, "key-1", later)
+	assert.NoError(t, err)
+	assert.False(t, stored.Completed())
+	assert.True(t, stored.CreatedAt.Equal(later))
+
+	retry.StatusCode = 201
+	retry.ExpiresAt = later.Add(time.Hour)
+	assert.NoError(t, repo.Complete(ctx, retry))
+	assert.NoError(t, repo.Release(ctx, retry))
+}
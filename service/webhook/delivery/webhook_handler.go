@@ -0,0 +1,127 @@
+package delivery
+
+import (
+	"strconv"
+
+	"itmx_test/domain"
+	"itmx_test/middleware"
+	"itmx_test/problem"
+	"itmx_test/service/entity"
+	"itmx_test/service/webhook/usecase"
+	"itmx_test/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookHandler struct {
+	wu usecase.WebhookUsecase
+}
+
+func NewWebhookHandler(f *fiber.App, wu usecase.WebhookUsecase) {
+	handler := &WebhookHandler{wu}
+
+	// group name, managing webhooks is reserved for admins
+	webhook := f.Group("/webhooks", middleware.AdminOnly())
+
+	// Subscription
+	webhook.Post("", handler.CreateSubscription)
+	webhook.Get("", handler.ListSubscriptions)
+
+	// Delivery
+	webhook.Post("/deliveries/:id/retry", handler.RetryDelivery)
+
+	webhook.Get("/:id", handler.GetSubscription)
+	webhook.Get("/:id/deliveries", handler.ListDeliveries)
+	webhook.Delete("/:id", handler.DeleteSubscription)
+}
+
+type SubscriptionBody struct {
+	URL    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=customer.created customer.updated customer.deleted"`
+	Secret string   `json:"secret" validate:"omitempty,min=16,max=256"`
+}
+
+// SubscriptionCreated is the only response that carries the signing secret
+type SubscriptionCreated struct {
+	*entity.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+func (wh *WebhookHandler) CreateSubscription(c *fiber.Ctx) error {
+	var input SubscriptionBody
+
+	// Parser input
+	if err := c.BodyParser(&input); err != nil {
+		return problem.MalformedBody(err)
+	}
+
+	// Validate input
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	subscription := &entity.WebhookSubscription{
+		URL:    input.URL,
+		Events: input.Events,
+		Secret: input.Secret,
+	}
+
+	if err := wh.wu.CreateSubscription(c.UserContext(), subscription); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(SubscriptionCreated{
+		WebhookSubscription: subscription,
+		Secret:              subscription.Secret,
+	})
+}
+
+func (wh *WebhookHandler) ListSubscriptions(c *fiber.Ctx) error {
+	subscriptions, err := wh.wu.GetSubscriptions(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(subscriptions)
+}
+
+func (wh *WebhookHandler) GetSubscription(c *fiber.Ctx) error {
+	subscription, err := wh.wu.GetSubscriptionByID(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(subscription)
+}
+
+func (wh *WebhookHandler) DeleteSubscription(c *fiber.Ctx) error {
+	if err := wh.wu.DelSubscriptionByID(c.UserContext(), c.Params("id")); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ListDeliveries is the delivery log of a subscription, newest first, with every attempt made
+func (wh *WebhookHandler) ListDeliveries(c *fiber.Ctx) error {
+	deliveries, err := wh.wu.GetDeliveries(c.UserContext(), c.Params("id"), c.QueryInt("limit"))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(deliveries)
+}
+
+// RetryDelivery requeues a dead-lettered delivery
+func (wh *WebhookHandler) RetryDelivery(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return domain.ErrBadParamInput
+	}
+
+	if err := wh.wu.RetryDelivery(c.UserContext(), uint(id)); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
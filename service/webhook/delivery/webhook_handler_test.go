@@ -0,0 +1,208 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"itmx_test/domain"
+	"itmx_test/middleware"
+	"itmx_test/service/entity"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookService) CreateSubscription(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	args := m.Called(subscription)
+	subscription.ID = "sub-1"
+	subscription.Active = true
+	if subscription.Secret == "" {
+		subscription.Secret = "whsec_generated"
+	}
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) GetSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error) {
+	args := m.Called()
+	if subscriptions, ok := args.Get(0).([]*entity.WebhookSubscription); ok {
+		return subscriptions, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) GetSubscriptionByID(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	args := m.Called(id)
+	if subscription, ok := args.Get(0).(*entity.WebhookSubscription); ok {
+		return subscription, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) DelSubscriptionByID(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) GetDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*entity.WebhookDelivery, error) {
+	args := m.Called(subscriptionID, limit)
+	if deliveries, ok := args.Get(0).([]*entity.WebhookDelivery); ok {
+		return deliveries, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) RetryDelivery(ctx context.Context, id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+const adminToken = "test-admin-token"
+
+func newTestApp(mockService *MockWebhookService) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Use(middleware.AdminMiddleware(adminToken))
+	NewWebhookHandler(app, mockService)
+	return app
+}
+
+func TestWebhookHandlerRequiresAdmin(t *testing.T) {
+	mockService := new(MockWebhookService)
+	app := newTestApp(mockService)
+
+	req := httptest.NewRequest("GET", "/webhooks", nil)
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	mockService.AssertNotCalled(t, "GetSubscriptions")
+}
+
+func TestCreateSubscriptionHandler(t *testing.T) {
+	mockService := new(MockWebhookService)
+	app := newTestApp(mockService)
+
+	t.Run("returns secret once", func(t *testing.T) {
+		mockService.On("CreateSubscription", mock.AnythingOfType("*entity.WebhookSubscription")).Return(nil).Once()
+
+		body, _ := json.Marshal(SubscriptionBody{URL: "https://example.com/hook", Events: []string{entity.EventCustomerCreated}})
+		req := httptest.NewRequest("POST", "/webhooks", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Admin-Token", adminToken)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		result := map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Equal(t, "sub-1", result["id"])
+		assert.Equal(t, "whsec_generated", result["secret"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid event", func(t *testing.T) {
+		body, _ := json.Marshal(SubscriptionBody{URL: "https://example.com/hook", Events: []string{"customer.exploded"}})
+		req := httptest.NewRequest("POST", "/webhooks", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Admin-Token", adminToken)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		body, _ := json.Marshal(SubscriptionBody{URL: "not a url", Events: []string{entity.EventCustomerCreated}})
+		req := httptest.NewRequest("POST", "/webhooks", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Admin-Token", adminToken)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestGetSubscriptionHandler(t *testing.T) {
+	mockService := new(MockWebhookService)
+	app := newTestApp(mockService)
+
+	t.Run("hides secret", func(t *testing.T) {
+		mockService.On("GetSubscriptionByID", "sub-1").Return(&entity.WebhookSubscription{ID: "sub-1", Secret: "whsec_hidden"}, nil)
+
+		req := httptest.NewRequest("GET", "/webhooks/sub-1", nil)
+		req.Header.Set("X-Admin-Token", adminToken)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		body, _ := io.ReadAll(resp.Body)
+		assert.NotContains(t, string(body), "whsec_hidden")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockService.On("GetSubscriptionByID", "missing").Return(nil, domain.ErrNotFound)
+
+		req := httptest.NewRequest("GET", "/webhooks/missing", nil)
+		req.Header.Set("X-Admin-Token", adminToken)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestListDeliveriesHandler(t *testing.T) {
+	mockService := new(MockWebhookService)
+	app := newTestApp(mockService)
+
+	deliveries := []*entity.WebhookDelivery{{ID: 1, SubscriptionID: "sub-1", Status: entity.DeliveryDead, Attempts: 8}}
+	mockService.On("GetDeliveries", "sub-1", 10).Return(deliveries, nil)
+
+	req := httptest.NewRequest("GET", "/webhooks/sub-1/deliveries?limit=10", nil)
+	req.Header.Set("X-Admin-Token", adminToken)
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	result := []*entity.WebhookDelivery{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, entity.DeliveryDead, result[0].Status)
+	mockService.AssertExpectations(t)
+}
+
+func TestRetryDeliveryHandler(t *testing.T) {
+	mockService := new(MockWebhookService)
+	app := newTestApp(mockService)
+
+	t.Run("success", func(t *testing.T) {
+		mockService.On("RetryDelivery", uint(7)).Return(nil)
+
+		req := httptest.NewRequest("POST", "/webhooks/deliveries/7/retry", nil)
+		req.Header.Set("X-Admin-Token", adminToken)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/webhooks/deliveries/abc/retry", nil)
+		req.Header.Set("X-Admin-Token", adminToken)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"itmx_test/domain"
+	"itmx_test/service/entity"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository interface {
+	// Subscription
+	CreateSubscription(ctx context.Context, subscription *entity.WebhookSubscription) error
+	FindSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error)
+	FindSubscriptionByID(ctx context.Context, id string) (*entity.WebhookSubscription, error)
+	FindActiveSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error)
+	DeleteSubscriptionByID(ctx context.Context, id string) error
+
+	// Outbox
+	FindPendingEvents(ctx context.Context, limit int) ([]*entity.OutboxEvent, error)
+	EnqueueDeliveries(ctx context.Context, event *entity.OutboxEvent, deliveries []*entity.WebhookDelivery) error
+
+	// Delivery
+	FindDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error)
+	FindDeliveriesBySubscriptionID(ctx context.Context, id string, limit int) ([]*entity.WebhookDelivery, error)
+	SaveAttempt(ctx context.Context, delivery *entity.WebhookDelivery, attempt *entity.WebhookAttempt) error
+	RequeueDelivery(ctx context.Context, id uint, now time.Time) error
+}
+
+type webhookRepo struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepo{db}
+}
+
+func (wr *webhookRepo) CreateSubscription(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	return wr.db.WithContext(ctx).Create(subscription).Error
+}
+
+func (wr *webhookRepo) FindSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error) {
+	subscriptions := []*entity.WebhookSubscription{}
+	if err := wr.db.WithContext(ctx).Order("created_at asc").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (wr *webhookRepo) FindSubscriptionByID(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	subscription := &entity.WebhookSubscription{}
+	if err := wr.db.WithContext(ctx).Where("id = ?", id).First(subscription).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (wr *webhookRepo) FindActiveSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error) {
+	subscriptions := []*entity.WebhookSubscription{}
+	if err := wr.db.WithContext(ctx).Where("active = ?", true).Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (wr *webhookRepo) DeleteSubscriptionByID(ctx context.Context, id string) error {
+	result := wr.db.WithContext(ctx).Where("id = ?", id).Delete(&entity.WebhookSubscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// FindPendingEvents returns outbox events that have not been fanned out yet, oldest first
+func (wr *webhookRepo) FindPendingEvents(ctx context.Context, limit int) ([]*entity.OutboxEvent, error) {
+	events := []*entity.OutboxEvent{}
+	if err := wr.db.WithContext(ctx).Where("processed_at IS NULL").Order("id asc").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// EnqueueDeliveries creates the deliveries for an event and marks it processed in one
+// transaction, so an event is fanned out exactly once
+func (wr *webhookRepo) EnqueueDeliveries(ctx context.Context, event *entity.OutboxEvent, deliveries []*entity.WebhookDelivery) error {
+	return wr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(deliveries) > 0 {
+			if err := tx.Create(deliveries).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		result := tx.Model(&entity.OutboxEvent{}).
+			Where("id = ? AND processed_at IS NULL", event.ID).
+			Update("processed_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// already fanned out by someone else, drop the deliveries we just created
+			return domain.ErrConflict
+		}
+
+		event.ProcessedAt = &now
+		return nil
+	})
+}
+
+func (wr *webhookRepo) FindDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error) {
+	deliveries := []*entity.WebhookDelivery{}
+	if err := wr.db.WithContext(ctx).Where("status = ? AND next_attempt_at <= ?", entity.DeliveryPending, now).
+		Order("next_attempt_at asc, id asc").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (wr *webhookRepo) FindDeliveriesBySubscriptionID(ctx context.Context, id string, limit int) ([]*entity.WebhookDelivery, error) {
+	deliveries := []*entity.WebhookDelivery{}
+	if err := wr.db.WithContext(ctx).Preload("AttemptLog", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id asc")
+	}).Where("subscription_id = ?", id).Order("id desc").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// SaveAttempt stores the outcome of a delivery attempt together with the delivery's new state
+func (wr *webhookRepo) SaveAttempt(ctx context.Context, delivery *entity.WebhookDelivery, attempt *entity.WebhookAttempt) error {
+	return wr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		attempt.DeliveryID = delivery.ID
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(delivery).Select("status", "attempts", "next_attempt_at", "last_error").Updates(delivery).Error
+	})
+}
+
+// RequeueDelivery puts a dead-lettered delivery back in the queue with a fresh attempt budget
+func (wr *webhookRepo) RequeueDelivery(ctx context.Context, id uint, now time.Time) error {
+	result := wr.db.WithContext(ctx).Model(&entity.WebhookDelivery{}).
+		Where("id = ? AND status = ?", id, entity.DeliveryDead).
+		Updates(map[string]interface{}{
+			"status":          entity.DeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
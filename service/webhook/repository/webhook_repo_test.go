@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"itmx_test/domain"
+	"itmx_test/service/entity"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newMockRepo(t *testing.T) (WebhookRepository, sqlmock.Sqlmock) {
+	// Mock database
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	// Expectation for the sqlite version check
+	mock.ExpectQuery("select sqlite_version()").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.31.1"))
+
+	dialector := sqlite.Dialector{Conn: sqlDB}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm database: %v", err)
+	}
+
+	return NewWebhookRepository(gormDB), mock
+}
+
+func TestFindSubscriptionByID(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	// Success case
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "url", "secret", "events", "active"}).
+			AddRow("sub-1", "http://example.com/hook", "secret", `["customer.created"]`, true)
+		mock.ExpectQuery("SELECT \\* FROM `webhook_subscriptions` WHERE id = \\?").WithArgs("sub-1").WillReturnRows(rows)
+
+		subscription, err := repo.FindSubscriptionByID(context.Background(), "sub-1")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{entity.EventCustomerCreated}, subscription.Events)
+		assert.True(t, subscription.Subscribes(entity.EventCustomerCreated))
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Not found case
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM `webhook_subscriptions`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := repo.FindSubscriptionByID(context.Background(), "missing")
+		assert.Equal(t, domain.ErrNotFound, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// A database failure is not a missing subscription
+	t.Run("failure", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM `webhook_subscriptions`").WillReturnError(gorm.ErrInvalidDB)
+
+		_, err := repo.FindSubscriptionByID(context.Background(), "sub-1")
+		assert.ErrorIs(t, err, gorm.ErrInvalidDB)
+		assert.NotErrorIs(t, err, domain.ErrNotFound)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestEnqueueDeliveries(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	deliveries := func() []*entity.WebhookDelivery {
+		return []*entity.WebhookDelivery{{SubscriptionID: "sub-1", EventID: 1, Status: entity.DeliveryPending}}
+	}
+
+	// Success case
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `webhook_deliveries`").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE `outbox_events` SET `processed_at`=\\? WHERE id = \\? AND processed_at IS NULL").
+			WithArgs(sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		event := &entity.OutboxEvent{ID: 1}
+		err := repo.EnqueueDeliveries(context.Background(), event, deliveries())
+		assert.NoError(t, err)
+		assert.NotNil(t, event.ProcessedAt)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Already processed case rolls the deliveries back
+	t.Run("already processed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `webhook_deliveries`").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec("UPDATE `outbox_events`").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.EnqueueDeliveries(context.Background(), &entity.OutboxEvent{ID: 1}, deliveries())
+		assert.Equal(t, domain.ErrConflict, err)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSaveAttempt(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `webhook_attempts`").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE `webhook_deliveries` SET `status`=\\?,`attempts`=\\?,`next_attempt_at`=\\?,`last_error`=\\?,`updated_at`=\\? WHERE `id` = \\?").
+			WithArgs(entity.DeliveryDead, 8, sqlmock.AnyArg(), "boom", sqlmock.AnyArg(), 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		delivery := &entity.WebhookDelivery{ID: 3, Status: entity.DeliveryDead, Attempts: 8, LastError: "boom", NextAttemptAt: time.Now()}
+		attempt := &entity.WebhookAttempt{StatusCode: 500, Error: "boom"}
+		err := repo.SaveAttempt(context.Background(), delivery, attempt)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(3), attempt.DeliveryID)
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRequeueDelivery(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `webhook_deliveries` SET .* WHERE id = \\? AND status = \\?").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.RequeueDelivery(context.Background(), 1, time.Now()))
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// Not dead lettered case
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `webhook_deliveries`").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		assert.Equal(t, domain.ErrNotFound, repo.RequeueDelivery(context.Background(), 2, time.Now()))
+
+		// Ensure all expectations were met
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
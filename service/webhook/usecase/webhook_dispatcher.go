@@ -0,0 +1,242 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"itmx_test/domain"
+	"itmx_test/logging"
+	"itmx_test/service/entity"
+	"itmx_test/service/webhook/repository"
+)
+
+const (
+	HeaderWebhookID        = "X-Webhook-ID"
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+var now = time.Now
+
+type DispatcherConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	Timeout      time.Duration
+}
+
+func (dc DispatcherConfig) withDefaults() DispatcherConfig {
+	if dc.PollInterval <= 0 {
+		dc.PollInterval = 2 * time.Second
+	}
+	if dc.BatchSize <= 0 {
+		dc.BatchSize = 50
+	}
+	if dc.MaxAttempts <= 0 {
+		dc.MaxAttempts = 8
+	}
+	if dc.BaseBackoff <= 0 {
+		dc.BaseBackoff = 10 * time.Second
+	}
+	if dc.MaxBackoff <= 0 {
+		dc.MaxBackoff = time.Hour
+	}
+	if dc.Timeout <= 0 {
+		dc.Timeout = 10 * time.Second
+	}
+	return dc
+}
+
+// WebhookDispatcher fans outbox events out to subscriptions and delivers them. Failed deliveries
+// are retried with exponential backoff and dead-lettered after MaxAttempts.
+type WebhookDispatcher struct {
+	webhookRepo repository.WebhookRepository
+	client      *http.Client
+	config      DispatcherConfig
+}
+
+func NewWebhookDispatcher(webhookRepo repository.WebhookRepository, config DispatcherConfig) *WebhookDispatcher {
+	config = config.withDefaults()
+	return &WebhookDispatcher{
+		webhookRepo: webhookRepo,
+		client:      &http.Client{Timeout: config.Timeout},
+		config:      config,
+	}
+}
+
+// Run dispatches until ctx is cancelled. A pass in progress then stops after the delivery it is
+// making, which runs to completion: aborting the request would cost the delivery an attempt.
+func (wd *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(wd.config.PollInterval)
+	defer ticker.Stop()
+
+	work := context.WithoutCancel(ctx)
+	for {
+		if err := wd.dispatch(work, ctx.Done()); err != nil {
+			logging.FromContext(ctx).WithField("component", "webhook_dispatcher").WithError(err).Error("dispatch webhooks")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce fans out pending outbox events and makes one attempt at every due delivery
+func (wd *WebhookDispatcher) DispatchOnce(ctx context.Context) error {
+	return wd.dispatch(ctx, ctx.Done())
+}
+
+// dispatch makes one pass with ctx, checking stop between deliveries
+func (wd *WebhookDispatcher) dispatch(ctx context.Context, stop <-chan struct{}) error {
+	if err := wd.fanOut(ctx); err != nil {
+		return err
+	}
+
+	deliveries, err := wd.webhookRepo.FindDueDeliveries(ctx, now(), wd.config.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		if err := wd.deliver(ctx, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (wd *WebhookDispatcher) fanOut(ctx context.Context) error {
+	events, err := wd.webhookRepo.FindPendingEvents(ctx, wd.config.BatchSize)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	subscriptions, err := wd.webhookRepo.FindActiveSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		deliveries := []*entity.WebhookDelivery{}
+		for _, subscription := range subscriptions {
+			if !subscription.Subscribes(event.EventType) {
+				continue
+			}
+			deliveries = append(deliveries, &entity.WebhookDelivery{
+				SubscriptionID: subscription.ID,
+				EventID:        event.ID,
+				EventType:      event.EventType,
+				Payload:        event.Payload,
+				Status:         entity.DeliveryPending,
+				NextAttemptAt:  now(),
+			})
+		}
+
+		err := wd.webhookRepo.EnqueueDeliveries(ctx, event, deliveries)
+		if err != nil && !errors.Is(err, domain.ErrConflict) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (wd *WebhookDispatcher) deliver(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	subscription, err := wd.webhookRepo.FindSubscriptionByID(ctx, delivery.SubscriptionID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+
+	attempt := &entity.WebhookAttempt{}
+	start := now()
+
+	if subscription == nil || !subscription.Active {
+		attempt.Error = "subscription is no longer active"
+	} else {
+		attempt.StatusCode, attempt.Error = wd.post(ctx, subscription, delivery)
+	}
+	attempt.DurationMs = now().Sub(start).Milliseconds()
+
+	delivery.Attempts++
+	switch {
+	case attempt.Error == "":
+		delivery.Status = entity.DeliverySucceeded
+		delivery.LastError = ""
+	case subscription == nil || !subscription.Active || delivery.Attempts >= wd.config.MaxAttempts:
+		delivery.Status = entity.DeliveryDead
+		delivery.LastError = attempt.Error
+	default:
+		delivery.LastError = attempt.Error
+		delivery.NextAttemptAt = now().Add(wd.backoff(delivery.Attempts))
+	}
+
+	return wd.webhookRepo.SaveAttempt(ctx, delivery, attempt)
+}
+
+func (wd *WebhookDispatcher) post(ctx context.Context, subscription *entity.WebhookSubscription, delivery *entity.WebhookDelivery) (int, string) {
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookID, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderWebhookEvent, delivery.EventType)
+	req.Header.Set(HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(HeaderWebhookSignature, "sha256="+Sign(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := wd.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, ""
+}
+
+// backoff doubles the wait after every failed attempt, capped at MaxBackoff
+func (wd *WebhookDispatcher) backoff(attempts int) time.Duration {
+	wait := wd.config.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= wd.config.MaxBackoff {
+			return wd.config.MaxBackoff
+		}
+	}
+	return wait
+}
+
+// Sign computes the hex HMAC-SHA256 of "timestamp.payload". Receivers recompute it with their
+// secret and compare it to the X-Webhook-Signature header.
+func Sign(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"itmx_test/service/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receiver is an httptest webhook endpoint that verifies signatures and answers with the
+// queued status codes, then 200
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+	valid    []bool
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	signature := "sha256=" + Sign(r.secret, req.Header.Get(HeaderWebhookTimestamp), string(body))
+
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(body))
+	r.valid = append(r.valid, req.Header.Get(HeaderWebhookSignature) == signature)
+
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+// setClock freezes the package clock at start and returns a function to move it forward
+func setClock(t *testing.T, start time.Time) func(d time.Duration) {
+	current := start
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+	return func(d time.Duration) { current = current.Add(d) }
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	payload := `{"type":"customer.created"}`
+
+	newRepo := func(url string) *mockWebhookRepo {
+		return &mockWebhookRepo{
+			subscriptions: []*entity.WebhookSubscription{
+				{ID: "sub-1", URL: url, Secret: "top-secret", Events: []string{entity.EventCustomerCreated}, Active: true},
+				{ID: "sub-2", URL: url, Secret: "other", Events: []string{entity.EventCustomerDeleted}, Active: true},
+			},
+			events: []*entity.OutboxEvent{
+				{ID: 1, EventType: entity.EventCustomerCreated, CustomerID: "123", Payload: payload},
+			},
+		}
+	}
+
+	config := DispatcherConfig{MaxAttempts: 3, BaseBackoff: time.Second, MaxBackoff: time.Minute}
+
+	t.Run("delivers signed payload to matching subscriptions", func(t *testing.T) {
+		setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		rec := &receiver{secret: "top-secret"}
+		server := httptest.NewServer(rec)
+		defer server.Close()
+
+		repo := newRepo(server.URL)
+		dispatcher := NewWebhookDispatcher(repo, config)
+
+		assert.NoError(t, dispatcher.DispatchOnce(context.Background()))
+
+		assert.NotNil(t, repo.events[0].ProcessedAt)
+		assert.Len(t, repo.deliveries, 1)
+		assert.Equal(t, "sub-1", repo.deliveries[0].SubscriptionID)
+		assert.Equal(t, entity.DeliverySucceeded, repo.deliveries[0].Status)
+		assert.Equal(t, 1, repo.deliveries[0].Attempts)
+
+		assert.Len(t, rec.requests, 1)
+		assert.True(t, rec.valid[0])
+		assert.Equal(t, payload, rec.bodies[0])
+		assert.Equal(t, entity.EventCustomerCreated, rec.requests[0].Header.Get(HeaderWebhookEvent))
+		assert.Equal(t, "1704067200", rec.requests[0].Header.Get(HeaderWebhookTimestamp))
+
+		assert.Len(t, repo.attempts, 1)
+		assert.Equal(t, http.StatusOK, repo.attempts[0].StatusCode)
+
+		// the event is fanned out only once
+		assert.NoError(t, dispatcher.DispatchOnce(context.Background()))
+		assert.Len(t, repo.deliveries, 1)
+		assert.Len(t, rec.requests, 1)
+	})
+
+	t.Run("retries with backoff", func(t *testing.T) {
+		advance := setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		rec := &receiver{secret: "top-secret", statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}}
+		server := httptest.NewServer(rec)
+		defer server.Close()
+
+		repo := newRepo(server.URL)
+		dispatcher := NewWebhookDispatcher(repo, config)
+
+		assert.NoError(t, dispatcher.DispatchOnce(context.Background()))
+		delivery := repo.deliveries[0]
+		assert.Equal(t, entity.DeliveryPending, delivery.Status)
+		assert.Equal(t, "unexpected status 500", delivery.LastError)
+		assert.Equal(t, now().Add(time.Second), delivery.NextAttemptAt)
+
+		// not due yet
+		assert.NoError(t, dispatcher.DispatchOnce(context.Background()))
+		assert.Len(t, rec.requests, 1)
+
+		advance(time.Second)
+		assert.NoError(t, dispatcher.DispatchOnce(context.Background()))
+		assert.Equal(t, 2, delivery.Attempts)
+		assert.Equal(t, now().Add(2*time.Second), delivery.NextAttemptAt)
+
+		advance(2 * time.Second)
+		assert.NoError(t, dispatcher.DispatchOnce(context.Background()))
+		assert.Equal(t, entity.DeliverySucceeded, delivery.Status)
+		assert.Equal(t, 3, delivery.Attempts)
+		assert.Len(t, delivery.AttemptLog, 3)
+	})
+
+	t.Run("dead letters after max attempts", func(t *testing.T) {
+		advance := setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		rec := &receiver{secret: "top-secret", statuses: []int{500, 500, 500, 500}}
+		server := httptest.NewServer(rec)
+		defer server.Close()
+
+		repo := newRepo(server.URL)
+		dispatcher := NewWebhookDispatcher(repo, config)
+
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, dispatcher.DispatchOnce(context.Background()))
+			advance(time.Minute)
+		}
+
+		delivery := repo.deliveries[0]
+		assert.Equal(t, entity.DeliveryDead, delivery.Status)
+		assert.Equal(t, 3, delivery.Attempts)
+		assert.Len(t, rec.requests, 3)
+
+		// a manual retry gives it a fresh attempt budget
+		assert.NoError(t, NewWebhookUsecase(repo).RetryDelivery(context.Background(), delivery.ID))
+		assert.NoError(t, dispatcher.DispatchOnce(context.Background()))
+		assert.Equal(t, entity.DeliveryPending, delivery.Status)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.Len(t, rec.requests, 4)
+	})
+
+	t.Run("inactive subscription is dead lettered", func(t *testing.T) {
+		setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		repo := &mockWebhookRepo{
+			subscriptions: []*entity.WebhookSubscription{{ID: "sub-1", Active: false}},
+			deliveries:    []*entity.WebhookDelivery{{ID: 1, SubscriptionID: "sub-1", Status: entity.DeliveryPending}},
+		}
+		dispatcher := NewWebhookDispatcher(repo, config)
+
+		assert.NoError(t, dispatcher.DispatchOnce(context.Background()))
+		assert.Equal(t, entity.DeliveryDead, repo.deliveries[0].Status)
+		assert.Equal(t, "subscription is no longer active", repo.deliveries[0].LastError)
+	})
+
+	t.Run("database failure keeps the delivery pending", func(t *testing.T) {
+		setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		rec := &receiver{secret: "top-secret"}
+		server := httptest.NewServer(rec)
+		defer server.Close()
+
+		repo := &mockWebhookRepo{
+			subscriptions:       []*entity.WebhookSubscription{{ID: "sub-1", URL: server.URL, Secret: "top-secret", Active: true}},
+			deliveries:          []*entity.WebhookDelivery{{ID: 1, SubscriptionID: "sub-1", Payload: payload, Status: entity.DeliveryPending}},
+			findSubscriptionErr: errors.New("database is locked"),
+		}
+		dispatcher := NewWebhookDispatcher(repo, config)
+
+		assert.Error(t, dispatcher.DispatchOnce(context.Background()))
+		assert.Equal(t, entity.DeliveryPending, repo.deliveries[0].Status)
+		assert.Equal(t, 0, repo.deliveries[0].Attempts)
+		assert.Empty(t, repo.attempts)
+
+		// delivered once the database recovers
+		repo.findSubscriptionErr = nil
+		assert.NoError(t, dispatcher.DispatchOnce(context.Background()))
+		assert.Equal(t, entity.DeliverySucceeded, repo.deliveries[0].Status)
+		assert.Len(t, rec.requests, 1)
+	})
+
+	t.Run("stopping completes the delivery in flight", func(t *testing.T) {
+		setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		received := make(chan struct{})
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(received)
+			<-release
+		}))
+		defer server.Close()
+
+		repo := newRepo(server.URL)
+		dispatcher := NewWebhookDispatcher(repo, config)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			dispatcher.Run(ctx)
+		}()
+
+		<-received
+		cancel()
+		close(release)
+		<-done
+
+		assert.Equal(t, entity.DeliverySucceeded, repo.deliveries[0].Status)
+		assert.Len(t, repo.attempts, 1)
+		assert.Empty(t, repo.attempts[0].Error)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	dispatcher := NewWebhookDispatcher(&mockWebhookRepo{}, DispatcherConfig{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second})
+
+	assert.Equal(t, time.Second, dispatcher.backoff(1))
+	assert.Equal(t, 2*time.Second, dispatcher.backoff(2))
+	assert.Equal(t, 8*time.Second, dispatcher.backoff(4))
+	assert.Equal(t, 10*time.Second, dispatcher.backoff(5))
+	assert.Equal(t, 10*time.Second, dispatcher.backoff(40))
+}
@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"itmx_test/service/entity"
+	"itmx_test/service/webhook/repository"
+	"itmx_test/util"
+)
+
+type WebhookUsecase interface {
+	CreateSubscription(ctx context.Context, subscription *entity.WebhookSubscription) error
+	GetSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error)
+	GetSubscriptionByID(ctx context.Context, id string) (*entity.WebhookSubscription, error)
+	DelSubscriptionByID(ctx context.Context, id string) error
+	GetDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*entity.WebhookDelivery, error)
+	RetryDelivery(ctx context.Context, id uint) error
+}
+
+type webhookUsecase struct {
+	webhookRepo repository.WebhookRepository
+}
+
+func NewWebhookUsecase(webhookRepo repository.WebhookRepository) WebhookUsecase {
+	return &webhookUsecase{webhookRepo}
+}
+
+// CreateSubscription registers the subscription, generating a signing secret when none is given
+func (wu *webhookUsecase) CreateSubscription(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	subscription.ID = util.GenerateUuid()
+	subscription.Active = true
+
+	if subscription.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return err
+		}
+		subscription.Secret = secret
+	}
+
+	if err := wu.webhookRepo.CreateSubscription(ctx, subscription); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (wu *webhookUsecase) GetSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error) {
+	subscriptions, err := wu.webhookRepo.FindSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (wu *webhookUsecase) GetSubscriptionByID(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	subscription, err := wu.webhookRepo.FindSubscriptionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func (wu *webhookUsecase) DelSubscriptionByID(ctx context.Context, id string) error {
+	if err := wu.webhookRepo.DeleteSubscriptionByID(ctx, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (wu *webhookUsecase) GetDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*entity.WebhookDelivery, error) {
+	if _, err := wu.webhookRepo.FindSubscriptionByID(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	if limit < 1 || limit > 100 {
+		limit = 100
+	}
+
+	deliveries, err := wu.webhookRepo.FindDeliveriesBySubscriptionID(ctx, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// RetryDelivery requeues a dead-lettered delivery for immediate delivery
+func (wu *webhookUsecase) RetryDelivery(ctx context.Context, id uint) error {
+	if err := wu.webhookRepo.RequeueDelivery(ctx, id, now()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,191 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"itmx_test/domain"
+	"itmx_test/service/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockWebhookRepo is an in-memory WebhookRepository
+type mockWebhookRepo struct {
+	subscriptions []*entity.WebhookSubscription
+	events        []*entity.OutboxEvent
+	deliveries    []*entity.WebhookDelivery
+	attempts      []*entity.WebhookAttempt
+
+	// findSubscriptionErr is returned by FindSubscriptionByID when set
+	findSubscriptionErr error
+}
+
+func (m *mockWebhookRepo) CreateSubscription(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	m.subscriptions = append(m.subscriptions, subscription)
+	return nil
+}
+
+func (m *mockWebhookRepo) FindSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error) {
+	return m.subscriptions, nil
+}
+
+func (m *mockWebhookRepo) FindSubscriptionByID(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	if m.findSubscriptionErr != nil {
+		return nil, m.findSubscriptionErr
+	}
+	for _, subscription := range m.subscriptions {
+		if subscription.ID == id {
+			return subscription, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockWebhookRepo) FindActiveSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error) {
+	subscriptions := []*entity.WebhookSubscription{}
+	for _, subscription := range m.subscriptions {
+		if subscription.Active {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (m *mockWebhookRepo) DeleteSubscriptionByID(ctx context.Context, id string) error {
+	for i, subscription := range m.subscriptions {
+		if subscription.ID == id {
+			m.subscriptions = append(m.subscriptions[:i], m.subscriptions[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+func (m *mockWebhookRepo) FindPendingEvents(ctx context.Context, limit int) ([]*entity.OutboxEvent, error) {
+	events := []*entity.OutboxEvent{}
+	for _, event := range m.events {
+		if event.ProcessedAt == nil && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *mockWebhookRepo) EnqueueDeliveries(ctx context.Context, event *entity.OutboxEvent, deliveries []*entity.WebhookDelivery) error {
+	if event.ProcessedAt != nil {
+		return domain.ErrConflict
+	}
+	for _, delivery := range deliveries {
+		delivery.ID = uint(len(m.deliveries) + 1)
+		m.deliveries = append(m.deliveries, delivery)
+	}
+	processedAt := now()
+	event.ProcessedAt = &processedAt
+	return nil
+}
+
+func (m *mockWebhookRepo) FindDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error) {
+	deliveries := []*entity.WebhookDelivery{}
+	for _, delivery := range m.deliveries {
+		if delivery.Status == entity.DeliveryPending && !delivery.NextAttemptAt.After(now) && len(deliveries) < limit {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func (m *mockWebhookRepo) FindDeliveriesBySubscriptionID(ctx context.Context, id string, limit int) ([]*entity.WebhookDelivery, error) {
+	deliveries := []*entity.WebhookDelivery{}
+	for _, delivery := range m.deliveries {
+		if delivery.SubscriptionID == id {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func (m *mockWebhookRepo) SaveAttempt(ctx context.Context, delivery *entity.WebhookDelivery, attempt *entity.WebhookAttempt) error {
+	attempt.DeliveryID = delivery.ID
+	m.attempts = append(m.attempts, attempt)
+	delivery.AttemptLog = append(delivery.AttemptLog, attempt)
+	return nil
+}
+
+func (m *mockWebhookRepo) RequeueDelivery(ctx context.Context, id uint, now time.Time) error {
+	for _, delivery := range m.deliveries {
+		if delivery.ID == id && delivery.Status == entity.DeliveryDead {
+			delivery.Status = entity.DeliveryPending
+			delivery.Attempts = 0
+			delivery.NextAttemptAt = now
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+func TestCreateSubscription(t *testing.T) {
+	t.Run("generates id and secret", func(t *testing.T) {
+		repo := &mockWebhookRepo{}
+		usecase := NewWebhookUsecase(repo)
+
+		subscription := &entity.WebhookSubscription{URL: "http://example.com/hook", Events: []string{entity.EventCustomerCreated}}
+		err := usecase.CreateSubscription(context.Background(), subscription)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, subscription.ID)
+		assert.True(t, subscription.Active)
+		assert.True(t, strings.HasPrefix(subscription.Secret, "whsec_"))
+		assert.Len(t, repo.subscriptions, 1)
+	})
+
+	t.Run("keeps given secret", func(t *testing.T) {
+		repo := &mockWebhookRepo{}
+		usecase := NewWebhookUsecase(repo)
+
+		subscription := &entity.WebhookSubscription{URL: "http://example.com/hook", Secret: "my-own-secret-value"}
+		assert.NoError(t, usecase.CreateSubscription(context.Background(), subscription))
+		assert.Equal(t, "my-own-secret-value", subscription.Secret)
+	})
+}
+
+func TestGetDeliveries(t *testing.T) {
+	t.Run("unknown subscription", func(t *testing.T) {
+		usecase := NewWebhookUsecase(&mockWebhookRepo{})
+
+		_, err := usecase.GetDeliveries(context.Background(), "missing", 10)
+		assert.Equal(t, domain.ErrNotFound, err)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		repo := &mockWebhookRepo{
+			subscriptions: []*entity.WebhookSubscription{{ID: "sub-1"}},
+			deliveries: []*entity.WebhookDelivery{
+				{ID: 1, SubscriptionID: "sub-1"},
+				{ID: 2, SubscriptionID: "sub-2"},
+			},
+		}
+		usecase := NewWebhookUsecase(repo)
+
+		deliveries, err := usecase.GetDeliveries(context.Background(), "sub-1", 10)
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 1)
+	})
+}
+
+func TestRetryDelivery(t *testing.T) {
+	repo := &mockWebhookRepo{
+		deliveries: []*entity.WebhookDelivery{
+			{ID: 1, Status: entity.DeliveryDead, Attempts: 8},
+			{ID: 2, Status: entity.DeliverySucceeded, Attempts: 1},
+		},
+	}
+	usecase := NewWebhookUsecase(repo)
+
+	assert.NoError(t, usecase.RetryDelivery(context.Background(), 1))
+	assert.Equal(t, entity.DeliveryPending, repo.deliveries[0].Status)
+	assert.Equal(t, 0, repo.deliveries[0].Attempts)
+
+	assert.Equal(t, domain.ErrNotFound, usecase.RetryDelivery(context.Background(), 2))
+}
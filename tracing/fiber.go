@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fiberCarrier reads the trace context from the request headers and writes it to the
+// response headers
+type fiberCarrier struct {
+	c *fiber.Ctx
+}
+
+func (fc fiberCarrier) Get(key string) string {
+	return fc.c.Get(key)
+}
+
+func (fc fiberCarrier) Set(key, value string) {
+	fc.c.Set(key, value)
+}
+
+func (fc fiberCarrier) Keys() []string {
+	keys := []string{}
+	fc.c.Request().Header.VisitAll(func(key, value []byte) {
+		keys = append(keys, strings.ToLower(string(key)))
+	})
+	return keys
+}
+
+// Middleware starts a server span for every request, continuing the trace of an incoming
+// traceparent header. The span is put in c.UserContext, so it must run before the middleware
+// deriving contexts from it, and its traceparent is returned to the caller.
+func Middleware() fiber.Handler {
+	var once sync.Once
+	routes := map[string]bool{}
+
+	return func(c *fiber.Ctx) error {
+		once.Do(func() {
+			for _, r := range c.App().GetRoutes(true) {
+				routes[r.Method+" "+r.Path] = true
+			}
+		})
+
+		propagator := otel.GetTextMapPropagator()
+		ctx := propagator.Extract(c.UserContext(), fiberCarrier{c})
+
+		// fiber reuses the request buffers, the span outlives the request
+		method := utils.CopyString(c.Method())
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(utils.CopyString(c.Path())),
+			),
+		)
+		defer span.End()
+
+		c.SetUserContext(ctx)
+		propagator.Inject(ctx, fiberCarrier{c})
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if fiberErr, ok := err.(*fiber.Error); ok {
+				status = fiberErr.Code
+			}
+			span.RecordError(err)
+		}
+
+		if r := c.Route(); routes[r.Method+" "+r.Path] {
+			span.SetName(method + " " + r.Path)
+			span.SetAttributes(semconv.HTTPRoute(r.Path))
+		}
+		span.SetAttributes(attribute.Int(string(semconv.HTTPResponseStatusCodeKey), status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, utils.StatusMessage(status))
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,146 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// record installs a tracer provider keeping the ended spans in memory
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := record(t)
+
+	f := fiber.New()
+	f.Use(Middleware())
+	f.Get("/things/:id", func(c *fiber.Ctx) error {
+		_, span := Start(c.UserContext(), "child")
+		End(span, nil)
+		return c.SendString("ok")
+	})
+	f.Get("/broken", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest("GET", "/things/1", nil)
+	req.Header.Set("traceparent", traceparent)
+	resp, err := f.Test(req)
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	child, server := spans[0], spans[1]
+
+	assert.Equal(t, "GET /things/:id", server.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, int64(200), attributeValue(server, "http.response.status_code").AsInt64())
+	assert.Equal(t, "/things/:id", attributeValue(server, "http.route").AsString())
+	assert.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+
+	// the caller gets the trace context of the server span
+	assert.True(t, strings.HasPrefix(resp.Header.Get("traceparent"), "00-4bf92f3577b34da6a3ce929d0e0e4736-"+server.SpanContext().SpanID().String()))
+
+	_, err = f.Test(httptest.NewRequest("GET", "/broken", nil))
+	require.NoError(t, err)
+	assert.Equal(t, codes.Error, recorder.Ended()[2].Status().Code)
+}
+
+func TestGormPlugin(t *testing.T) {
+	recorder := record(t)
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "itmx.sqlite")), &gorm.Config{})
+	require.NoError(t, err)
+
+	type thing struct {
+		ID   uint
+		Name string
+	}
+	require.NoError(t, db.AutoMigrate(&thing{}))
+	require.NoError(t, db.Use(GormPlugin{}))
+
+	ctx, parent := Start(context.Background(), "parent")
+	require.NoError(t, db.WithContext(ctx).Create(&thing{Name: "a"}).Error)
+	assert.Error(t, db.WithContext(ctx).Exec("SELECT * FROM missing").Error)
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	create := spans[0]
+	assert.Equal(t, "gorm.create", create.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), create.Parent().SpanID())
+	assert.Contains(t, attributeValue(create, "db.statement").AsString(), "INSERT INTO `things`")
+	assert.Equal(t, "things", attributeValue(create, "db.sql.table").AsString())
+	assert.Equal(t, codes.Unset, create.Status().Code)
+
+	raw := spans[1]
+	assert.Equal(t, "gorm.raw", raw.Name())
+	assert.Equal(t, codes.Error, raw.Status().Code)
+}
+
+func TestEnd(t *testing.T) {
+	recorder := record(t)
+
+	_, span := Start(context.Background(), "failing")
+	End(span, errors.New("boom"))
+
+	ended := recorder.Ended()[0]
+	assert.Equal(t, codes.Error, ended.Status().Code)
+	assert.Equal(t, "boom", ended.Status().Description)
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterFile, File: file, SampleRatio: 1, ServiceName: "itmx"})
+	require.NoError(t, err)
+
+	_, span := Start(context.Background(), "exported")
+	End(span, nil)
+	require.NoError(t, shutdown(context.Background()))
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"Name":"exported"`)
+
+	_, err = Setup(context.Background(), Config{Exporter: "zipkin"})
+	assert.ErrorContains(t, err, `unsupported trace exporter "zipkin"`)
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"itmx_test/domain"
+)
+
+// cursorSecret signs pagination cursors, there is no default so cursors are never signed with a
+// known key
+var cursorSecret []byte
+
+// errNoCursorSecret is returned while no secret is set, it is a server error
+var errNoCursorSecret = errors.New("pagination cursor secret is not set")
+
+type cursorPayload struct {
+	After string `json:"a"`
+}
+
+// SetCursorSecret sets the key used to sign pagination cursors, an empty secret unsets it
+func SetCursorSecret(secret string) {
+	cursorSecret = []byte(secret)
+}
+
+// EncodeCursor builds an opaque, signed token pointing after the given id. It fails while no
+// secret is set.
+func EncodeCursor(afterID string) (string, error) {
+	if len(cursorSecret) == 0 {
+		return "", errNoCursorSecret
+	}
+
+	payload, _ := json.Marshal(cursorPayload{After: afterID})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signCursor(encoded)), nil
+}
+
+// DecodeCursor verifies the token signature and returns the id it points after. It fails while
+// no secret is set.
+func DecodeCursor(token string) (string, error) {
+	if len(cursorSecret) == 0 {
+		return "", errNoCursorSecret
+	}
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", domain.ErrInvalidCursor
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, signCursor(encoded)) {
+		return "", domain.ErrInvalidCursor
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", domain.ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.After == "" {
+		return "", domain.ErrInvalidCursor
+	}
+
+	return payload.After, nil
+}
+
+func signCursor(encoded string) []byte {
+	mac := hmac.New(sha256.New, cursorSecret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
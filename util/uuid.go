@@ -2,15 +2,30 @@ package util
 
 import (
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	idMu     sync.Mutex
+	lastIDAt time.Time
+)
+
+// GenerateUuid returns a random id prefixed with its creation time, YYYYMMDDhhmmss followed by
+// the nanoseconds. The prefix grows with every call, so ids generated later by this process
+// always sort after the earlier ones, which cursor pagination relies on.
 func GenerateUuid() string {
-	uuid := uuid.New().String()
-	now := time.Now().Format("2006-01-02 15:04:05")
-	now = strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(now, "-", ""), ":", ""), " ", "")
-	result := now + "-" + uuid
-	return result
+	idMu.Lock()
+	// compare wall clock readings, they are what the prefix is made of
+	now := time.Now().Round(0)
+	if !now.After(lastIDAt) {
+		now = lastIDAt.Add(time.Nanosecond)
+	}
+	lastIDAt = now
+	idMu.Unlock()
+
+	prefix := strings.Replace(now.Format("20060102150405.000000000"), ".", "", 1)
+	return prefix + "-" + uuid.New().String()
 }
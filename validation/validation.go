@@ -1,4 +1,4 @@
-package middleware
+package validation
 
 import (
 	"fmt"
@@ -12,6 +12,7 @@ func Validate(s interface{}) error {
 	return validate.Struct(s)
 }
 
+// ErrorResponse lists the failed rules of a Validate error by field name
 func ErrorResponse(err error) map[string]interface{} {
 	validationErrors := err.(validator.ValidationErrors)
 	errorMessages := make(map[string]interface{})